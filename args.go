@@ -29,6 +29,11 @@ Then the CommandLine function will produce the following string:
 	-vvv -a name:Jesse -a "surname:van den Kieboom" --name="Sergey Makinen"
 
 Any type that implements the Marshaler interface may fully customize its value output.
+Types implementing the encoding.TextMarshaler or fmt.Stringer interfaces are also supported
+(see Config.ValueSources).
+
+Structs defined for other command-line parsers, such as the kong package,
+may be converted by translating their struct field tags (see Config.TagDialect and KongConfig).
 
 
 Arguments, options and conventions
@@ -60,16 +65,41 @@ of The GNU C Library Reference Manual (see https://www.gnu.org/software/libc/man
 
 Supported field tags
 
-The following is a list of supported struct field tags (which is a subset of supported tags by the flags package):
+The following is a list of supported struct field tags (which is a subset of supported tags by the flags package).
+Tag values are used as is, including commas, so `default:"a,b"` defines the default value 'a,b':
 
-At least one is required:
+At least one is required, unless Config.NamingStrategy is set:
 
-    short:               the short name of the option (single character)
+    short:               the short name of the option (single character).
+                         This tag can be specified multiple times to define aliases
 
-    long:                the long name of the option
+    long:                the long name of the option.
+                         This tag can be specified multiple times to define aliases
 
 Optional:
 
+    preferred:           the long or short name written in preference to other aliases
+                         of the option (see Config.PreferredNames). This tag can be specified
+                         multiple times
+
+    required:            if non-empty, makes the option or the positional argument required.
+                         When specified on a positional argument with a slice type,
+                         the value may be the minimum number of elements (see Check)
+
+    choice:              limits the values of the option to the set of values (see Check).
+                         This tag can be specified multiple times
+
+    count:               if non-empty, makes the option with an integer type counted:
+                         the option is repeated the number of times, e.g. '-vvv'
+
+    negate:              the argument is written instead of a boolean option
+                         having the true default value to turn it off, e.g. '--no-color'
+                         (see Config.NegationPrefix)
+
+    negation-prefix:     the prefix is written before the long name of a boolean option
+                         having the true default value to turn it off, unless the option
+                         has the negate tag (see Config.NegationPrefix)
+
     no-flag:             if non-empty, this field is ignored
 
     optional:            if non-empty, makes the value of the option optional.
@@ -81,8 +111,63 @@ Optional:
     default:             the default value of the argument. This tag can be specified
                          multiple times in case of slices or maps
 
+    key-value-delimiter: the delimiter is written between a key and a value of a map option
+                         (see Config.KeyValueDelimiter)
+
+    since:               the version of the command the option or the positional argument
+                         was added in (see Config.TargetVersion)
+
+    until:               the version of the command the option or the positional argument
+                         was removed in (see Config.TargetVersion)
+
+    renamed-from:        the former long name of the option and the version of the command
+                         it was renamed in, e.g. 'colour@2.0' (see Config.TargetVersion).
+                         This tag can be specified multiple times
+
+    env:                 the name of the environment variable of the option (see Config.Env)
+
+    env-delim:           the delimiter is written between values of the environment variable
+                         of a slice or map option
+
+    delivery:            specifies how the option having the env tag is passed to a command:
+                         "flag", "env" or "both" (see Config.Delivery)
+
+    join:                the separator is written between values of a slice or map option,
+                         so the values are written as a single argument. If "none",
+                         the option is repeated for every value (see Config.JoinSeparator)
+
     positional-args:     when specified on a field with a struct type, uses the fields
                          of that struct (in order of the fields) as positional arguments
+
+    positional:          if non-empty, makes the field a positional argument
+                         (in order of the fields)
+
+    group:               when specified on a field with a struct type, makes the struct
+                         a group of options
+
+    namespace:           when specified on a group field, prefixes long option names
+                         of the group (including nested groups) with the namespace
+                         (see Config.NamespaceDelimiter)
+
+    prefix:              when specified on a group field, prefixes long option names
+                         of the group (including nested groups) with the value as is, e.g. 'db-'
+
+    env-namespace:       when specified on a group field, prefixes environment variable names
+                         of the group (including nested groups) with the namespace
+                         (see Config.EnvNamespaceDelimiter)
+
+    env-prefix:          when specified on a group field, prefixes environment variable names
+                         of the group (including nested groups) with the value as is, e.g. 'DB_'
+
+    command:             when specified on a field with a struct type, makes the struct
+                         a (sub)command with the given name. The name of the active command
+                         is written after the options and positional arguments of its parent command
+                         (see Config.CommandPath)
+
+    alias:               an alias of the command. This tag can be specified multiple times
+
+    subcommands-optional: when specified on a command field, makes its subcommands optional.
+                         Otherwise, an active subcommand is required
 */
 package cmdbuilder
 
@@ -91,6 +176,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"github.com/sergeymakinen/go-cmdbuilder/v2/internal/argv"
 )
 
 // FieldError represents an error when converting struct fields.
@@ -98,11 +185,43 @@ type FieldError struct {
 	Struct reflect.Type // type of the struct containing the field
 	Field  string       // name of the field
 	Type   reflect.Type // type of the field
+	Index  interface{}  // index of the slice element or key of the map entry, if any
 	Msg    string       // description of error
+	Err    error        // underlying error, if any
 }
 
 func (e *FieldError) Error() string {
-	return fmt.Sprintf("failed to convert struct field %s.%s of type %s: %s", e.Struct, e.Field, e.Type, e.Msg)
+	field := e.Field
+	if e.Index != nil {
+		field += fmt.Sprintf("[%#v]", e.Index)
+	}
+	if isConstraintError(e.Err) {
+		return fmt.Sprintf("invalid value of struct field %s.%s of type %s: %s", e.Struct, field, e.Type, e.Msg)
+	}
+	return fmt.Sprintf("failed to convert struct field %s.%s of type %s: %s", e.Struct, field, e.Type, e.Msg)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// FieldErrors represents multiple errors when converting struct fields.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors.
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
 }
 
 // Quoter returns s quoted such that it appears correctly
@@ -129,11 +248,32 @@ type Config struct {
 	// before a long option name.
 	LongOptionDelimiter string
 
+	// AttachShortOptionArgument specifies whether to write an argument
+	// of a short option in the same command-line argument.
+	//
+	// Thus, the option 'o' with the argument 'foo' will become '-ofoo'.
+	AttachShortOptionArgument bool
+
+	// CombineShortOptionWithArgument specifies whether to write
+	// a short option taking an argument as the last one of combined
+	// short options, followed by the argument.
+	//
+	// Thus, '-x -z -f archive.tar' will become '-xzf archive.tar'.
+	CombineShortOptionWithArgument bool
+
+	// SingleDashLongOptions specifies whether to write long options
+	// with ShortOptionDelimiter, as the flag package and Java tools expect.
+	//
+	// Thus, the option 'name' will become '-name'. Short options
+	// are never combined and an error is returned if a short name
+	// of an option is the same as a long name of another one.
+	SingleDashLongOptions bool
+
 	// OptionArgumentDelimiter defines the delimiter is written
-	// between an option and its argument.
+	// between an option and its argument. If empty, space is used.
 	//
 	// Thus, the option 'o' with the argument 'foo' will become
-	// '-o foo', if the delimiter is space.
+	// '-o foo', if the delimiter is space, or '-o=foo', if the delimiter is '='.
 	OptionArgumentDelimiter string
 
 	// OptionOptionalArgumentDelimiter defines the delimiter is written
@@ -150,8 +290,125 @@ type Config struct {
 	// if the terminator is '--'.
 	OptionsTerminator string
 
+	// OptionsTerminatorIfNeeded specifies whether to write OptionsTerminator
+	// only if some positional argument begins with ShortOptionDelimiter
+	// or LongOptionDelimiter.
+	//
+	// Thus, '--a bc de -f' will become '-f --a bc de',
+	// but '--a bc -de -f' will become '-f --a -- bc -de'.
+	OptionsTerminatorIfNeeded bool
+
+	// KeyValueDelimiter defines the delimiter is written between
+	// a key and a value of a map option, unless the option has
+	// the key-value-delimiter tag. If empty, ':' is used.
+	//
+	// Thus, the map option 'env' with the key 'foo' and the value 'bar'
+	// will become '--env foo=bar', if the delimiter is '='.
+	KeyValueDelimiter string
+
+	// JoinSeparator, if not empty, defines the separator is written between
+	// values of a slice or map option, unless the option has the join tag,
+	// so the values are written as a single argument.
+	//
+	// Thus, the option 'tag' with the values 'a' and 'b' will become
+	// '--tag a,b' instead of '--tag a --tag b', if the separator is ','.
+	JoinSeparator string
+
+	// NamespaceDelimiter defines the delimiter is written between
+	// namespaces of groups and a long option name,
+	// as flags.Parser.NamespaceDelimiter does. If empty, '.' is used.
+	//
+	// Thus, the option 'host' in the group with the namespace 'db'
+	// will become '--db.host'.
+	NamespaceDelimiter string
+
+	// EnvNamespaceDelimiter defines the delimiter is written between
+	// environment namespaces of groups and an environment variable name,
+	// as flags.Parser.EnvNamespaceDelimiter does. If empty, '_' is used.
+	EnvNamespaceDelimiter string
+
+	// Delivery specifies how options having the env tag are passed
+	// to a command, unless an option has the delivery tag.
+	Delivery Delivery
+
+	// NegationPrefix, if not empty, defines the prefix is written
+	// before a long name of a boolean option having the true default value
+	// to turn it off, unless the option has the negate tag.
+	//
+	// Thus, the option 'color' with the false value will become '--no-color',
+	// if the prefix is 'no-'. Otherwise, it will become '--color=false'.
+	NegationPrefix string
+
 	// ArgumentQuoter, if not nil, is applied to option and positional arguments.
 	ArgumentQuoter Quoter
+
+	// ValueSources defines the interfaces checked in order
+	// to convert a value to a string, before falling back to its kind.
+	//
+	// If nil, Marshaler, encoding.TextMarshaler and fmt.Stringer
+	// are checked in order. If empty, no interface is checked.
+	ValueSources []ValueSource
+
+	// TargetVersion, if not empty, defines the dotted numeric version of the command
+	// (e.g. '2.3') used to check the since and until tags of options and
+	// positional arguments, and to select their long names from the renamed-from tags.
+	//
+	// Options and positional arguments not supported by the version are skipped,
+	// if they don't have values. Otherwise, ErrUnsupportedVersion is returned,
+	// unless DropUnsupported is true.
+	TargetVersion string
+
+	// DropUnsupported specifies whether to silently skip options and positional arguments
+	// having values, while they are not supported by TargetVersion.
+	DropUnsupported bool
+
+	// NamingStrategy, if not nil, derives long names of options
+	// from names of exported struct fields having neither short nor long names
+	// (see KebabCase, SnakeCase and CamelCase). Fields having the no-flag tag,
+	// embedded fields and fields of types which values can't be converted
+	// to arguments are still skipped.
+	//
+	// Thus, the untagged field 'HTTPProxy' will become '--http-proxy',
+	// if the strategy is KebabCase.
+	NamingStrategy NamingStrategy
+
+	// PreferredNames defines long and short names written in preference
+	// to other names of an option having multiple long or short names,
+	// unless NameSelector selects a name.
+	//
+	// Otherwise, a name from the preferred tag is written, if any,
+	// or the first specified name.
+	PreferredNames []string
+
+	// NameSelector, if not nil, selects the name written for an option
+	// having multiple long or short names.
+	NameSelector NameSelector
+
+	// TagDialect, if not nil, translates struct field tags to the notation
+	// of the flags package (see KongDialect). If nil, FlagsDialect is used.
+	//
+	// TagKeyDialect and TagPrefixDialect allow to separate the tags
+	// from tags of other packages defined on the same struct.
+	TagDialect TagDialect
+
+	// CommandPath defines names (or aliases) of the active command
+	// and its subcommands, e.g. []string{"remote", "add"}.
+	//
+	// Commands not listed are active if defined by a non-nil pointer
+	// to a struct and no other command at the same level is such.
+	CommandPath []string
+
+	// Validate specifies whether to check the required and choice constraints
+	// of options and positional arguments before converting them (see Check).
+	Validate bool
+
+	// Strict specifies whether to return an error for every struct field
+	// having a type which values can't be converted to arguments
+	// (structs without a Marshaler, complex numbers, channels, functions,
+	// nested slices, etc.), even if the field has a zero value.
+	//
+	// Otherwise, such fields are silently skipped.
+	Strict bool
 }
 
 // Args converts the provided struct (or pointer to a struct) v
@@ -173,19 +430,83 @@ func (c *Config) CommandLine(v interface{}) (string, error) {
 }
 
 func (c *Config) args(v interface{}, cmdline bool) ([]string, error) {
-	parsed, err := parse(v)
+	parsed, err := c.parse(v)
 	if err != nil {
 		return nil, err
 	}
-	parsed, args := c.combineShorts(parsed)
+	return c.buildArgs(parsed, cmdline)
+}
+
+func (c *Config) buildArgs(parsed []arg, cmdline bool) ([]string, error) {
+	if c.Validate {
+		if err := validate(parsed); err != nil {
+			return nil, err
+		}
+	}
+	if c.SingleDashLongOptions {
+		if err := c.checkNameCollisions(parsed); err != nil {
+			return nil, err
+		}
+	}
+	var args []string
+	start := 0
+	for i := 0; i <= len(parsed); i++ {
+		if i < len(parsed) && !parsed[i].IsCommand() {
+			continue
+		}
+		scope, err := c.options(parsed[start:i], cmdline)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, scope...)
+		// Positional arguments of a command are written before its subcommand,
+		// as the flags package fills them before looking up subcommands.
+		var positional []string
+		for _, arg := range parsed[start:i] {
+			if arg.IsPositional() && arg.IsProvided() {
+				positional = append(positional, arg.Value()...)
+			}
+		}
+		if i == len(parsed) {
+			args = c.appendPositional(args, positional, cmdline)
+			break
+		}
+		for _, v := range positional {
+			if cmdline {
+				v = c.quote(v)
+			}
+			args = append(args, v)
+		}
+		args = append(args, parsed[i].Name())
+		start = i + 1
+	}
+	return args, nil
+}
+
+// appendPositional appends positional arguments to args
+// preceded by the options terminator, if needed.
+func (c *Config) appendPositional(args, positional []string, cmdline bool) []string {
+	return c.syntax().AppendPositional(args, positional, cmdline)
+}
+
+// options converts options of a single command to command-line arguments.
+func (c *Config) options(parsed []arg, cmdline bool) ([]string, error) {
+	parsed, args := c.combineShorts(parsed, cmdline)
 	var buf bytes.Buffer
 	for _, arg := range parsed {
-		if !arg.IsOption() || !arg.IsProvided() {
+		if !arg.IsFlag() || !arg.IsProvided() {
 			continue
 		}
+		if arg.IsNegated() {
+			if neg := c.negatedOption(arg); neg != "" {
+				args = append(args, neg)
+				continue
+			}
+		}
 		buf.Reset()
-		if c.DisableShortName || arg.ShortName() == "" || (arg.IsValueOptional() && arg.IsValueProvided()) {
-			buf.WriteString(c.LongOptionDelimiter)
+		isShort := !(c.DisableShortName || arg.ShortName() == "" || (arg.IsValueOptional() && arg.IsValueProvided()))
+		if !isShort {
+			buf.WriteString(c.longOptionDelimiter())
 			if arg.Name() == "" {
 				return nil, &FieldError{
 					Struct: arg.Struct(),
@@ -202,7 +523,7 @@ func (c *Config) args(v interface{}, cmdline bool) ([]string, error) {
 			buf.WriteString(c.ShortOptionDelimiter)
 			buf.WriteString(arg.ShortName())
 		}
-		for _, v := range arg.Value() {
+		for _, v := range arg.argValue() {
 			if arg.IsValueOptional() {
 				if arg.IsValueProvided() {
 					if c.OptionOptionalArgumentDelimiter == " " {
@@ -217,42 +538,79 @@ func (c *Config) args(v interface{}, cmdline bool) ([]string, error) {
 					}
 				} else {
 					args = append(args, buf.String())
-					if !c.DisableCombiningShortOptions {
+					if !c.DisableCombiningShortOptions && !arg.isRepeatable() {
 						break
 					}
 				}
 			} else {
-				args = append(args, buf.String())
-				if cmdline {
-					args = append(args, c.quote(v))
-				} else {
-					args = append(args, v)
-				}
+				args = append(args, c.optionArgument(buf.String(), v, isShort && c.AttachShortOptionArgument, cmdline)...)
 			}
 		}
 	}
+	return args, nil
+}
+
+// optionArgument returns the option opt followed by its argument v
+// either as separate arguments or as a single one, if attach is true.
+func (c *Config) optionArgument(opt, v string, attach, cmdline bool) []string {
+	return c.syntax().Option(opt, v, attach, cmdline)
+}
+
+func (c *Config) longOptionDelimiter() string {
+	if c.SingleDashLongOptions {
+		return c.ShortOptionDelimiter
+	}
+	return c.LongOptionDelimiter
+}
+
+// checkNameCollisions returns an error if a short name of an option
+// is the same as a long name of another option.
+func (c *Config) checkNameCollisions(parsed []arg) error {
+	longs := make(map[string]arg)
+	for _, arg := range parsed {
+		if arg.IsOption() && arg.Name() != "" {
+			longs[arg.Name()] = arg
+		}
+	}
 	for _, arg := range parsed {
-		if arg.IsOption() || !arg.IsProvided() {
+		if !arg.IsOption() || arg.ShortName() == "" {
 			continue
 		}
-		for _, v := range arg.Value() {
-			if cmdline {
-				args = append(args, c.quote(v))
-			} else {
-				args = append(args, v)
+		if other, ok := longs[arg.ShortName()]; ok && (other.st != arg.st || other.sf.Name != arg.sf.Name) {
+			return &FieldError{
+				Struct: arg.Struct(),
+				Field:  arg.Field().Name,
+				Type:   arg.Field().Type,
+				Msg:    fmt.Sprintf("short name %q collides with long name of %s.%s", arg.ShortName(), other.Struct(), other.Field().Name),
 			}
 		}
 	}
-	return args, nil
+	return nil
 }
 
-func (c *Config) combineShorts(parsed []arg) (rem []arg, args []string) {
-	if c.DisableCombiningShortOptions || c.DisableShortName {
+// negatedOption returns the option turning off the boolean option,
+// if it's defined by the negate tag or Config.NegationPrefix.
+func (c *Config) negatedOption(arg arg) string {
+	if neg := arg.tags.First("negate"); neg != "" {
+		return neg
+	}
+	prefix := arg.tags.First("negation-prefix")
+	if prefix == "" {
+		prefix = c.NegationPrefix
+	}
+	if prefix != "" && arg.Name() != "" {
+		return c.longOptionDelimiter() + prefix + arg.Name()
+	}
+	return ""
+}
+
+func (c *Config) combineShorts(parsed []arg, cmdline bool) (rem []arg, args []string) {
+	if c.DisableCombiningShortOptions || c.DisableShortName || c.SingleDashLongOptions {
 		return parsed, nil
 	}
 	var shorts []string
 	for _, arg := range parsed {
-		if arg.IsOption() && arg.IsProvided() && arg.IsValueOptional() && !arg.IsValueProvided() && arg.ShortName() != "" {
+		if arg.IsFlag() && arg.IsProvided() && arg.IsValueOptional() && !arg.IsValueProvided() && arg.ShortName() != "" {
 			for range arg.Value() {
 				shorts = append(shorts, arg.ShortName())
 			}
@@ -260,29 +618,55 @@ func (c *Config) combineShorts(parsed []arg) (rem []arg, args []string) {
 			rem = append(rem, arg)
 		}
 	}
-	if len(shorts) > 0 {
-		args = append(args, c.ShortOptionDelimiter+strings.Join(shorts, ""))
+	if len(shorts) == 0 {
+		return
+	}
+	cluster := c.ShortOptionDelimiter + strings.Join(shorts, "")
+	if c.CombineShortOptionWithArgument {
+		for i, arg := range rem {
+			if arg.IsFlag() && arg.IsProvided() && !arg.IsValueOptional() && !arg.IsNegated() && arg.ShortName() != "" && len(arg.argValue()) == 1 {
+				rem = append(rem[:i:i], rem[i+1:]...)
+				args = append(args, c.optionArgument(cluster+arg.ShortName(), arg.argValue()[0], c.AttachShortOptionArgument, cmdline)...)
+				return
+			}
+		}
 	}
+	args = append(args, cluster)
 	return
 }
 
+func (c *Config) namespaceDelimiter() string {
+	if c.NamespaceDelimiter == "" {
+		return "."
+	}
+	return c.NamespaceDelimiter
+}
+
 func (c *Config) quote(s string) string {
-	if c.ArgumentQuoter != nil {
-		return c.ArgumentQuoter(s)
+	return c.syntax().Quote(s)
+}
+
+func (c *Config) syntax() *argv.Syntax {
+	return &argv.Syntax{
+		ShortOptionDelimiter:      c.ShortOptionDelimiter,
+		LongOptionDelimiter:       c.longOptionDelimiter(),
+		OptionArgumentDelimiter:   c.OptionArgumentDelimiter,
+		OptionsTerminator:         c.OptionsTerminator,
+		OptionsTerminatorIfNeeded: c.OptionsTerminatorIfNeeded,
+		ArgumentQuoter:            c.ArgumentQuoter,
 	}
-	return s
 }
 
 // Args converts the provided struct (or pointer to a struct) v
 // defining command-line options and their values to command-line arguments
-// using a default configuration.
+// using a default configuration (WindowsConfig on Windows and UnixConfig otherwise).
 func Args(v interface{}) ([]string, error) {
 	return defaultConfig.Args(v)
 }
 
 // CommandLine converts the provided struct (or pointer to a struct) v
 // defining command-line options and their values to a command-line
-// using a default configuration.
+// using a default configuration (WindowsConfig on Windows and UnixConfig otherwise).
 func CommandLine(v interface{}) (string, error) {
 	return defaultConfig.CommandLine(v)
 }
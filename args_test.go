@@ -1,9 +1,13 @@
 package cmdbuilder
 
 import (
+	"flag"
+	"net"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
@@ -63,6 +67,310 @@ func TestArgsWithMaps(t *testing.T) {
 	testArgsAreEqual(t, []string{"--map", "baz:bar2", "--map", "foo:bar1"}, args, err)
 }
 
+func TestArgsWithNegatedBools(t *testing.T) {
+	s := struct {
+		Color    bool  `long:"color" default:"true"`
+		Pager    bool  `long:"pager" default:"true" negate:"--no-pager"`
+		Progress *bool `long:"progress" default:"true"`
+		Verbose  bool  `short:"v" long:"verbose"`
+		Enabled  bool  `long:"enabled" default:"true"`
+	}{
+		Color:    false,
+		Pager:    false,
+		Progress: new(bool),
+		Verbose:  true,
+		Enabled:  true,
+	}
+	args, err := testConfig.Args(s)
+	testArgsAreEqual(t, []string{"-v", "--color=false", "--no-pager", "--progress=false"}, args, err)
+
+	config := *testConfig
+	config.NegationPrefix = "no-"
+	args, err = config.Args(s)
+	testArgsAreEqual(t, []string{"-v", "--no-color", "--no-pager", "--no-progress"}, args, err)
+}
+
+func TestArgsWithCounts(t *testing.T) {
+	s := struct {
+		Verbose int   `short:"v" long:"verbose" count:"true"`
+		Quiet   uint8 `long:"quiet" count:"true"`
+		Force   bool  `short:"f"`
+		Debug   int   `short:"d" count:"true" default:"1"`
+		Level   int   `short:"l"`
+	}{
+		Verbose: 3,
+		Quiet:   2,
+		Force:   true,
+		Debug:   1,
+		Level:   2,
+	}
+	args, err := testConfig.Args(s)
+	testArgsAreEqual(t, []string{"-vvvf", "--quiet", "--quiet", "-l", "2"}, args, err)
+
+	config := *testConfig
+	config.DisableCombiningShortOptions = true
+	args, err = config.Args(s)
+	testArgsAreEqual(t, []string{"-v", "-v", "-v", "--quiet", "--quiet", "-f", "-l", "2"}, args, err)
+
+	config = *testConfig
+	config.DisableShortName = true
+	args, err = config.Args(struct {
+		Verbose int `short:"v" long:"verbose" count:"true"`
+	}{
+		Verbose: 2,
+	})
+	testArgsAreEqual(t, []string{"--verbose", "--verbose"}, args, err)
+
+	_, err = testConfig.Args(struct {
+		Verbose int `short:"v" count:"true"`
+	}{
+		Verbose: -1,
+	})
+	if err == nil || !strings.Contains(err.Error(), "negative count -1") {
+		t.Errorf("Config.Args() = _, %v; does not contain %q", err, "negative count -1")
+	}
+	_, err = testConfig.Args(struct {
+		Verbose string `short:"v" count:"true"`
+	}{})
+	if err == nil || !strings.Contains(err.Error(), "count option does not have integer type") {
+		t.Errorf("Config.Args() = _, %v; does not contain %q", err, "count option does not have integer type")
+	}
+}
+
+func TestArgsWithJoinSeparator(t *testing.T) {
+	s := struct {
+		Tags    []string          `long:"tag" join:","`
+		Labels  map[string]string `short:"l" join:";" key-value-delimiter:"="`
+		Hosts   []string          `long:"host"`
+		Ports   []int             `short:"p" join:"none"`
+		Verbose []bool            `short:"v"`
+		Empty   []string          `long:"empty" join:","`
+	}{
+		Tags:    []string{"a", "b", "c"},
+		Labels:  map[string]string{"foo": "1", "bar": "2"},
+		Hosts:   []string{"foo", "bar"},
+		Ports:   []int{80, 443},
+		Verbose: []bool{true, true},
+	}
+	args, err := testConfig.Args(s)
+	testArgsAreEqual(t, []string{"-vv", "--tag", "a,b,c", "-l", "bar=2;foo=1", "--host", "foo", "--host", "bar", "-p", "80", "-p", "443"}, args, err)
+
+	config := *testConfig
+	config.JoinSeparator = ":"
+	args, err = config.Args(s)
+	testArgsAreEqual(t, []string{"-vv", "--tag", "a,b,c", "-l", "bar=2;foo=1", "--host", "foo:bar", "-p", "80", "-p", "443"}, args, err)
+
+	s.Tags = append(s.Tags, "d,e")
+	_, err = testConfig.Args(s)
+	if ferr, ok := err.(*FieldError); !ok || ferr.Field != "Tags" || ferr.Index != 3 {
+		t.Errorf("Config.Args() = _, %v; want *FieldError for Tags[3]", err)
+	}
+}
+
+func TestArgsWithJoinSeparatorAndPositionalArgs(t *testing.T) {
+	var s struct {
+		Tags []string `long:"tag"`
+		Args struct {
+			Rest []string
+		} `positional-args:"true"`
+	}
+	s.Tags = []string{"a", "b"}
+	s.Args.Rest = []string{"x", "y"}
+	config := *testConfig
+	config.JoinSeparator = ","
+	args, err := config.Args(s)
+	testArgsAreEqual(t, []string{"--tag", "a,b", "x", "y"}, args, err)
+}
+
+func TestArgsWithJoinSeparatorAndValidate(t *testing.T) {
+	s := struct {
+		Animals []string `long:"animal" choice:"cat" choice:"dog" join:","`
+	}{
+		Animals: []string{"cat", "dog"},
+	}
+	config := *testConfig
+	config.Validate = true
+	args, err := config.Args(s)
+	testArgsAreEqual(t, []string{"--animal", "cat,dog"}, args, err)
+
+	s.Animals = append(s.Animals, "cow")
+	_, err = config.Args(s)
+	if errs, ok := err.(FieldErrors); !ok || len(errs) != 1 || errs[0].Index != 2 || errs[0].Err != ErrInvalidChoice {
+		t.Errorf("Config.Args() = _, %v; want FieldErrors with ErrInvalidChoice for Animals[2]", err)
+	}
+}
+
+type tarOptions struct {
+	Extract bool     `short:"x"`
+	Gzip    bool     `short:"z"`
+	File    string   `short:"f"`
+	Include []string `short:"I"`
+	Level   int      `short:"O" long:"level"`
+}
+
+func TestArgsWithShortOptionArguments(t *testing.T) {
+	opts := tarOptions{
+		Extract: true,
+		Gzip:    true,
+		File:    "archive.tar",
+		Include: []string{"include", "foo bar"},
+		Level:   2,
+	}
+	tests := []struct {
+		Name         string
+		Attach       bool
+		Combine      bool
+		ExpectedArgs []string
+		ExpectedCmd  string
+		NoRoundTrip  bool // the flags package supports attached arguments of first short options only
+	}{
+		{
+			Name:         "attach",
+			Attach:       true,
+			ExpectedArgs: []string{"-xz", "-farchive.tar", "-Iinclude", "-Ifoo bar", "-O2"},
+			ExpectedCmd:  `-xz -farchive.tar -Iinclude -I"foo bar" -O2`,
+		},
+		{
+			Name:         "combine",
+			Combine:      true,
+			ExpectedArgs: []string{"-xzf", "archive.tar", "-I", "include", "-I", "foo bar", "-O", "2"},
+			ExpectedCmd:  `-xzf archive.tar -I include -I "foo bar" -O 2`,
+		},
+		{
+			Name:         "attach and combine",
+			Attach:       true,
+			Combine:      true,
+			ExpectedArgs: []string{"-xzfarchive.tar", "-Iinclude", "-Ifoo bar", "-O2"},
+			ExpectedCmd:  `-xzfarchive.tar -Iinclude -I"foo bar" -O2`,
+			NoRoundTrip:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			config := *testConfig
+			config.AttachShortOptionArgument = tt.Attach
+			config.CombineShortOptionWithArgument = tt.Combine
+			config.ArgumentQuoter = UnixConfig.ArgumentQuoter
+			args, err := config.Args(opts)
+			testArgsAreEqual(t, tt.ExpectedArgs, args, err)
+			cmd, err := config.CommandLine(opts)
+			testCmdLineIsEqual(t, tt.ExpectedCmd, cmd, err)
+			if tt.NoRoundTrip {
+				return
+			}
+			var parsed tarOptions
+			if _, err := flags.ParseArgs(&parsed, args); err != nil {
+				t.Fatalf("flags.ParseArgs() = _, %v; want nil", err)
+			}
+			if !reflect.DeepEqual(parsed, opts) {
+				t.Errorf("flags.ParseArgs() parsed %+v; want %+v", parsed, opts)
+			}
+		})
+	}
+}
+
+func TestArgsWithSingleDashLongOptions(t *testing.T) {
+	s := struct {
+		Verbose bool     `short:"v" long:"verbose"`
+		Force   bool     `short:"f"`
+		Name    string   `long:"name"`
+		Tags    []string `short:"t" long:"tag"`
+		Color   bool     `long:"color" default:"true"`
+	}{
+		Verbose: true,
+		Force:   true,
+		Name:    "foo bar",
+		Tags:    []string{"a"},
+		Color:   false,
+	}
+	tests := []struct {
+		Name        string
+		Config      *Config
+		ExpectedCmd string
+	}{
+		{
+			Name: "space",
+			Config: &Config{
+				ShortOptionDelimiter:            "-",
+				LongOptionDelimiter:             "--",
+				SingleDashLongOptions:           true,
+				OptionArgumentDelimiter:         " ",
+				OptionOptionalArgumentDelimiter: "=",
+				ArgumentQuoter:                  UnixConfig.ArgumentQuoter,
+			},
+			ExpectedCmd: `-v -f -name "foo bar" -t a -color=false`,
+		},
+		{
+			Name:        "GoConfig",
+			Config:      GoConfig,
+			ExpectedCmd: `-v -f -name="foo bar" -t=a -color=false`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cmd, err := tt.Config.CommandLine(s)
+			testCmdLineIsEqual(t, tt.ExpectedCmd, cmd, err)
+		})
+	}
+
+	gs := struct {
+		Verbose    bool   `long:"verbose"`
+		Name       string `long:"name"`
+		Color      bool   `long:"color" default:"true"`
+		Positional struct {
+			Args []string
+		} `positional-args:"true"`
+	}{
+		Verbose: true,
+		Name:    "foo bar",
+		Color:   false,
+	}
+	gs.Positional.Args = []string{"-x"}
+	args, err := GoConfig.Args(gs)
+	testArgsAreEqual(t, []string{"-verbose", "-name=foo bar", "-color=false", "--", "-x"}, args, err)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := fs.Bool("verbose", false, "")
+	name := fs.String("name", "", "")
+	color := fs.Bool("color", true, "")
+	if err = fs.Parse(args); err != nil {
+		t.Fatalf("flag.FlagSet.Parse() = %v; want nil", err)
+	}
+	if !*verbose || *name != gs.Name || *color || !reflect.DeepEqual(fs.Args(), gs.Positional.Args) {
+		t.Errorf("flag.FlagSet.Parse() parsed verbose=%v name=%q color=%v args=%q", *verbose, *name, *color, fs.Args())
+	}
+
+	_, err = GoConfig.Args(struct {
+		Verbose bool `short:"v"`
+		Version bool `long:"v"`
+	}{})
+	if err == nil || !strings.Contains(err.Error(), `short name "v" collides with long name`) {
+		t.Errorf("Config.Args() = _, %v; does not contain %q", err, `short name "v" collides with long name`)
+	}
+}
+
+func TestArgsWithKeyValueDelimiter(t *testing.T) {
+	s := struct {
+		Env    map[string]string `short:"e" key-value-delimiter:"="`
+		Labels map[string]int    `long:"label"`
+	}{
+		Env:    map[string]string{"FOO": "bar:baz"},
+		Labels: map[string]int{"foo": 1},
+	}
+	args, err := testConfig.Args(s)
+	testArgsAreEqual(t, []string{"-e", "FOO=bar:baz", "--label", "foo:1"}, args, err)
+
+	config := *testConfig
+	config.KeyValueDelimiter = "/"
+	args, err = config.Args(s)
+	testArgsAreEqual(t, []string{"-e", "FOO=bar:baz", "--label", "foo/1"}, args, err)
+
+	s.Env["FOO=BAR"] = "baz"
+	_, err = testConfig.Args(s)
+	if ferr, ok := err.(*FieldError); !ok || ferr.Field != "Env" || ferr.Index != "FOO=BAR" {
+		t.Errorf("Config.Args() = _, %v; want *FieldError for Env[\"FOO=BAR\"]", err)
+	}
+}
+
 func TestArgsWithPointers(t *testing.T) {
 	bt1 := true
 	bt2 := &bt1
@@ -117,18 +425,224 @@ func TestArgsWithMarshaler(t *testing.T) {
 	testArgsAreEqual(t, []string{"--value", "success: ok"}, args, err)
 }
 
+type colorTest int
+
+func (c colorTest) String() string {
+	return [...]string{"red", "green", "blue"}[c]
+}
+
+type multiTest string
+
+func (m multiTest) MarshalFlag() (string, error) { return "flag: " + string(m), nil }
+
+func (m multiTest) MarshalText() ([]byte, error) { return []byte("text: " + m), nil }
+
+func (m multiTest) String() string { return "string: " + string(m) }
+
+func TestArgsWithValueSources(t *testing.T) {
+	u, _ := url.Parse("https://example.com/foo")
+	s := struct {
+		IP     net.IP      `long:"ip"`
+		URL    *url.URL    `long:"url"`
+		Colors []colorTest `long:"color"`
+		Multi  multiTest   `long:"multi"`
+	}{
+		IP:     net.IPv4(8, 8, 8, 8),
+		URL:    u,
+		Colors: []colorTest{1, 2},
+		Multi:  "foo",
+	}
+	tests := []struct {
+		Name         string
+		Sources      []ValueSource
+		ExpectedArgs []string
+	}{
+		{
+			Name:         "default",
+			Sources:      nil,
+			ExpectedArgs: []string{"--ip", "8.8.8.8", "--url", "https://example.com/foo", "--color", "green", "--color", "blue", "--multi", "flag: foo"},
+		},
+		{
+			Name:         "reordered",
+			Sources:      []ValueSource{StringerSource, TextMarshalerSource},
+			ExpectedArgs: []string{"--ip", "8.8.8.8", "--url", "https://example.com/foo", "--color", "green", "--color", "blue", "--multi", "string: foo"},
+		},
+		{
+			Name:         "text only",
+			Sources:      []ValueSource{TextMarshalerSource},
+			ExpectedArgs: []string{"--ip", "8.8.8.8", "--color", "1", "--color", "2", "--multi", "text: foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			config := *testConfig
+			config.ValueSources = tt.Sources
+			args, err := config.Args(&s)
+			testArgsAreEqual(t, tt.ExpectedArgs, args, err)
+		})
+	}
+}
+
+type ptrMarshalTest string
+
+func (m *ptrMarshalTest) MarshalFlag() (string, error) {
+	return "ptr: " + string(*m), nil
+}
+
+func TestArgsWithPointerReceiverMarshaler(t *testing.T) {
+	type S struct {
+		Value ptrMarshalTest            `long:"value"`
+		Array [1]ptrMarshalTest         `long:"array"`
+		Map   map[string]ptrMarshalTest `long:"map"`
+		Ptr   *ptrMarshalTest           `long:"ptr"`
+		URL   url.URL                   `long:"url"`
+	}
+	p := ptrMarshalTest("baz")
+	s := S{
+		Value: "foo",
+		Array: [1]ptrMarshalTest{"bar"},
+		Map:   map[string]ptrMarshalTest{"key": "value"},
+		Ptr:   &p,
+		URL:   url.URL{Scheme: "https", Host: "example.com"},
+	}
+	expected := []string{"--value", "ptr: foo", "--array", "ptr: bar", "--map", "key:ptr: value", "--ptr", "ptr: baz", "--url", "https://example.com"}
+	args, err := testConfig.Args(s)
+	testArgsAreEqual(t, expected, args, err)
+	args, err = testConfig.Args(&s)
+	testArgsAreEqual(t, expected, args, err)
+}
+
 func TestArgsShouldFailOnMarshalerError(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Struct interface{}
+		Field  string
+		Index  interface{}
+	}{
+		{
+			Name: "scalar",
+			Struct: struct {
+				Value marshalTest `long:"value"`
+			}{
+				Value: "fail",
+			},
+			Field: "Value",
+		},
+		{
+			Name: "slice",
+			Struct: struct {
+				Values []marshalTest `long:"value"`
+			}{
+				Values: []marshalTest{"ok", "fail"},
+			},
+			Field: "Values",
+			Index: 1,
+		},
+		{
+			Name: "map",
+			Struct: struct {
+				Values map[string]marshalTest `long:"value"`
+			}{
+				Values: map[string]marshalTest{"foo": "ok", "bar": "fail"},
+			},
+			Field: "Values",
+			Index: "bar",
+		},
+		{
+			Name: "positional",
+			Struct: struct {
+				Positional struct {
+					Arg marshalTest
+				} `positional-args:"true"`
+			}{
+				Positional: struct {
+					Arg marshalTest
+				}{
+					Arg: "fail",
+				},
+			},
+			Field: "Arg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := testConfig.Args(tt.Struct)
+			ferr, ok := err.(*FieldError)
+			if !ok {
+				t.Fatalf("Config.Args() = _, %v; want *FieldError", err)
+			}
+			if ferr.Field != tt.Field || ferr.Index != tt.Index {
+				t.Errorf("FieldError = {Field: %q, Index: %#v}; want {Field: %q, Index: %#v}", ferr.Field, ferr.Index, tt.Field, tt.Index)
+			}
+			if ferr.Err == nil || errors.Cause(ferr.Err).Error() != "error" {
+				t.Errorf("FieldError.Err = %v; want %q", ferr.Err, "error")
+			}
+			if _, err = testConfig.CommandLine(tt.Struct); err == nil {
+				t.Error("Config.CommandLine() = _, nil; want non-nil")
+			}
+		})
+	}
+}
+
+func TestArgsStrict(t *testing.T) {
+	type group struct {
+		Func func() `long:"func"`
+	}
 	s := struct {
-		Value marshalTest `long:"value"`
+		Complex    complex128              `long:"complex"`
+		Chan       chan int                `long:"chan"`
+		Struct     struct{ Foo string }    `long:"struct"`
+		Nested     [][]string              `long:"nested"`
+		Map        map[string][]string     `long:"map"`
+		Group      *group                  `group:"Group"`
+		Interface  interface{}             `long:"interface"`
+		Marshaler  marshalTest             `long:"marshaler"`
+		Duration   time.Duration           `long:"duration"`
+		Strings    []*string               `long:"strings"`
+		IntMap     map[string]int          `long:"int-map"`
+		Positional struct{ Arg complex64 } `positional-args:"true"`
 	}{
-		Value: "fail",
+		Interface: []string{"foo"},
+	}
+	config := *testConfig
+	config.Strict = true
+	_, err := config.Args(s)
+	errs, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatalf("Config.Args() = _, %v; want FieldErrors", err)
+	}
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	expected := []string{"Complex", "Chan", "Struct", "Nested", "Map", "Func", "Arg"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("FieldErrors fields = %v; want %v", fields, expected)
+	}
+
+	ok1 := struct {
+		Interface interface{}    `long:"interface"`
+		Duration  time.Duration  `long:"duration"`
+		Strings   []*string      `long:"strings"`
+		Ptr       ptrMarshalTest `long:"ptr"`
+	}{
+		Interface: "foo",
+		Duration:  time.Second,
+	}
+	args, err := config.Args(ok1)
+	testArgsAreEqual(t, []string{"--interface", "foo", "--duration", "1s"}, args, err)
+
+	bad := struct {
+		Interface interface{} `long:"interface"`
+	}{
+		Interface: []complex64{1},
+	}
+	if _, err = config.Args(bad); err == nil {
+		t.Error("Config.Args() = _, nil; want non-nil")
+	}
+	if _, err = testConfig.Args(bad); err != nil {
+		t.Errorf("Config.Args() = _, %v; want nil", err)
 	}
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("recover() = nil; want non-nil")
-		}
-	}()
-	testConfig.Args(s)
 }
 
 func TestArgsWithPositionalArgs(t *testing.T) {
@@ -284,6 +798,60 @@ func TestArgs_CommandLine_WithConfig(t *testing.T) {
 			ExpectedArgs: []string{"-bc", "--bool-false", "false", "-i", "1", "--int-optional", "1", "-q", "baz qux", "--string-optional", "foo", "--string-optional", "bar", "foo bar"},
 			ExpectedCmd:  "-bc --bool-false false -i 1 --int-optional 1 -q baz qux --string-optional foo --string-optional bar foo bar",
 		},
+		{
+			Name: "OptionsTerminator",
+			Config: Config{
+				OptionsTerminator: "--",
+			},
+			Struct:       s,
+			ExpectedArgs: []string{"-bc", "--bool-false=false", "-i", "1", "--int-optional=1", "-q", "baz qux", "--string-optional=foo", "--string-optional=bar", "--", "foo bar"},
+			ExpectedCmd:  "-bc --bool-false=false -i 1 --int-optional=1 -q baz qux --string-optional=foo --string-optional=bar -- foo bar",
+		},
+		{
+			Name: "OptionsTerminator without positional arguments",
+			Config: Config{
+				OptionsTerminator: "--",
+			},
+			Struct: struct {
+				Bool bool `short:"b"`
+			}{
+				Bool: true,
+			},
+			ExpectedArgs: []string{"-b"},
+			ExpectedCmd:  "-b",
+		},
+		{
+			Name: "OptionsTerminatorIfNeeded not needed",
+			Config: Config{
+				OptionsTerminator:         "--",
+				OptionsTerminatorIfNeeded: true,
+			},
+			Struct:       s,
+			ExpectedArgs: []string{"-bc", "--bool-false=false", "-i", "1", "--int-optional=1", "-q", "baz qux", "--string-optional=foo", "--string-optional=bar", "foo bar"},
+			ExpectedCmd:  "-bc --bool-false=false -i 1 --int-optional=1 -q baz qux --string-optional=foo --string-optional=bar foo bar",
+		},
+		{
+			Name: "OptionsTerminatorIfNeeded needed",
+			Config: Config{
+				OptionsTerminator:         "--",
+				OptionsTerminatorIfNeeded: true,
+			},
+			Struct: struct {
+				Bool       bool `short:"b"`
+				Positional struct {
+					Args []string
+				} `positional-args:"true"`
+			}{
+				Bool: true,
+				Positional: struct {
+					Args []string
+				}{
+					Args: []string{"foo", "-bar"},
+				},
+			},
+			ExpectedArgs: []string{"-b", "--", "foo", "-bar"},
+			ExpectedCmd:  "-b -- foo -bar",
+		},
 		{
 			Name: "ArgumentQuoter",
 			Config: Config{
@@ -323,6 +891,9 @@ func TestArgs_CommandLine_WithConfig(t *testing.T) {
 			if ct.Config.OptionsTerminator != "" {
 				config.OptionsTerminator = ct.Config.OptionsTerminator
 			}
+			if ct.Config.OptionsTerminatorIfNeeded {
+				config.OptionsTerminatorIfNeeded = ct.Config.OptionsTerminatorIfNeeded
+			}
 			if ct.Config.ArgumentQuoter != nil {
 				config.ArgumentQuoter = ct.Config.ArgumentQuoter
 			}
@@ -346,6 +917,322 @@ func TestArgs_CommandLine_WithConfig(t *testing.T) {
 	}
 }
 
+func TestConfigPresets(t *testing.T) {
+	s := struct {
+		Bool       bool   `short:"b"`
+		Verbose    bool   `short:"v"`
+		Name       string `long:"name"`
+		Positional struct {
+			Args []string
+		} `positional-args:"true"`
+	}{
+		Bool:    true,
+		Verbose: true,
+		Name:    "foo bar",
+		Positional: struct {
+			Args []string
+		}{
+			Args: []string{"-baz"},
+		},
+	}
+	tests := []struct {
+		Name        string
+		Config      *Config
+		ExpectedCmd string
+	}{
+		{
+			Name:        "UnixConfig",
+			Config:      UnixConfig,
+			ExpectedCmd: `-bv --name "foo bar" -baz`,
+		},
+		{
+			Name:        "GNUConfig",
+			Config:      GNUConfig,
+			ExpectedCmd: `-bv --name 'foo bar' -- -baz`,
+		},
+		{
+			Name:        "WindowsConfig",
+			Config:      WindowsConfig,
+			ExpectedCmd: `/b /v /name "foo bar" -baz`,
+		},
+		{
+			Name:        "ConfigFor(linux)",
+			Config:      ConfigFor("linux"),
+			ExpectedCmd: `-bv --name "foo bar" -baz`,
+		},
+		{
+			Name:        "ConfigFor(windows)",
+			Config:      ConfigFor("windows"),
+			ExpectedCmd: `/b /v /name "foo bar" -baz`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cmd, err := tt.Config.CommandLine(s)
+			testCmdLineIsEqual(t, tt.ExpectedCmd, cmd, err)
+		})
+	}
+	if c := ConfigFor("windows"); c == WindowsConfig {
+		t.Error("ConfigFor() = WindowsConfig; want copy")
+	}
+}
+
+type gitRemoteAddCommand struct {
+	Fetch      bool     `short:"f"`
+	Track      []string `short:"t" long:"track"`
+	Positional struct {
+		Name string
+		URL  string
+	} `positional-args:"true"`
+}
+
+type gitRemoteRemoveCommand struct {
+	Positional struct {
+		Name string
+	} `positional-args:"true"`
+}
+
+type gitRemoteCommand struct {
+	Verbose bool                   `short:"v" long:"verbose"`
+	Add     gitRemoteAddCommand    `command:"add"`
+	Remove  gitRemoteRemoveCommand `command:"remove" alias:"rm"`
+}
+
+type gitStatusCommand struct {
+	Short bool `short:"s" long:"short"`
+}
+
+type gitOptions struct {
+	Dir    string            `short:"C"`
+	Remote gitRemoteCommand  `command:"remote"`
+	Status *gitStatusCommand `command:"status"`
+}
+
+func TestArgsWithCommands(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Args         []string
+		ExpectedArgs []string
+	}{
+		{
+			Name:         "remote add",
+			Args:         []string{"-C", "repo", "remote", "-v", "add", "-f", "-t", "main", "origin", "https://example.com/repo.git"},
+			ExpectedArgs: []string{"-C", "repo", "remote", "-v", "add", "-f", "-t", "main", "origin", "https://example.com/repo.git"},
+		},
+		{
+			Name:         "remote rm",
+			Args:         []string{"remote", "rm", "origin"},
+			ExpectedArgs: []string{"remote", "remove", "origin"},
+		},
+		{
+			Name:         "status",
+			Args:         []string{"status", "--short"},
+			ExpectedArgs: []string{"status", "-s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			opts := gitOptions{Status: &gitStatusCommand{}}
+			parser := flags.NewParser(&opts, flags.None)
+			if _, err := parser.ParseArgs(tt.Args); err != nil {
+				t.Fatalf("flags.Parser.ParseArgs() = _, %v; want nil", err)
+			}
+			config := *testConfig
+			for cmd := parser.Active; cmd != nil; cmd = cmd.Active {
+				config.CommandPath = append(config.CommandPath, cmd.Name)
+			}
+			args, err := config.Args(opts)
+			testArgsAreEqual(t, tt.ExpectedArgs, args, err)
+		})
+	}
+	t.Run("active pointer", func(t *testing.T) {
+		opts := gitOptions{
+			Dir:    "repo",
+			Status: &gitStatusCommand{Short: true},
+		}
+		args, err := testConfig.Args(opts)
+		testArgsAreEqual(t, []string{"-C", "repo", "status", "-s"}, args, err)
+	})
+	t.Run("no active command", func(t *testing.T) {
+		args, err := testConfig.Args(gitOptions{Dir: "repo"})
+		testArgsAreEqual(t, []string{"-C", "repo"}, args, err)
+	})
+	t.Run("root and command positional args", func(t *testing.T) {
+		type addCommand struct {
+			Force bool `short:"f"`
+			Args  struct {
+				Name string
+			} `positional-args:"true"`
+		}
+		type rootOptions struct {
+			Verbose bool `short:"v"`
+			Args    struct {
+				File string
+			} `positional-args:"true"`
+			Add *addCommand `command:"add"`
+		}
+		opts := rootOptions{Verbose: true, Add: &addCommand{Force: true}}
+		opts.Args.File = "rootfile"
+		opts.Add.Args.Name = "-subname"
+		config := *testConfig
+		config.OptionsTerminator = "--"
+		config.OptionsTerminatorIfNeeded = true
+		args, err := config.Args(opts)
+		testArgsAreEqual(t, []string{"-v", "rootfile", "add", "-f", "--", "-subname"}, args, err)
+
+		parsed := rootOptions{Add: &addCommand{}}
+		if _, err = flags.ParseArgs(&parsed, args); err != nil {
+			t.Fatalf("flags.ParseArgs() = _, %v; want nil", err)
+		}
+		if !reflect.DeepEqual(parsed, opts) {
+			t.Errorf("flags.ParseArgs() = %+v; want %+v", parsed, opts)
+		}
+	})
+	errTests := []struct {
+		Name        string
+		CommandPath []string
+		Struct      interface{}
+		Err         string
+	}{
+		{
+			Name:        "unknown command",
+			CommandPath: []string{"fetch"},
+			Struct:      gitOptions{},
+			Err:         `unknown command "fetch"`,
+		},
+		{
+			Name:        "subcommand required",
+			CommandPath: []string{"remote"},
+			Struct:      gitOptions{},
+			Err:         `command "remote" requires a subcommand`,
+		},
+		{
+			Name: "multiple active commands",
+			Struct: struct {
+				Foo *struct{} `command:"foo"`
+				Bar *struct{} `command:"bar"`
+			}{
+				Foo: &struct{}{},
+				Bar: &struct{}{},
+			},
+			Err: `multiple active commands "foo" and "bar"`,
+		},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.Name, func(t *testing.T) {
+			config := *testConfig
+			config.CommandPath = tt.CommandPath
+			if _, err := config.Args(tt.Struct); err == nil || !strings.Contains(err.Error(), tt.Err) {
+				t.Errorf("Config.Args() = _, %v; does not contain %q", err, tt.Err)
+			}
+		})
+	}
+}
+
+type dbOptions struct {
+	Host string `long:"host"`
+	Port int    `short:"p" long:"port"`
+	TLS  struct {
+		Cert string `long:"cert"`
+	} `group:"TLS" namespace:"tls"`
+}
+
+type namespaceOptions struct {
+	Verbose bool      `short:"v" long:"verbose"`
+	DB      dbOptions `group:"Database" namespace:"db"`
+	Cache   struct {
+		Size int `long:"size"`
+	} `group:"Cache"`
+	Command struct {
+		DB dbOptions `group:"Database" namespace:"db"`
+	} `command:"run"`
+}
+
+func TestArgsWithNamespaces(t *testing.T) {
+	args := []string{"-v", "--db.host", "localhost", "--db.port", "5432", "--db.tls.cert", "cert.pem", "--size", "10", "run", "--db.host", "remote"}
+	tests := []struct {
+		Name         string
+		Delimiter    string
+		Args         []string
+		ExpectedArgs []string
+	}{
+		{
+			Name:         "default",
+			Args:         args,
+			ExpectedArgs: []string{"-v", "--db.host", "localhost", "-p", "5432", "--db.tls.cert", "cert.pem", "--size", "10", "run", "--db.host", "remote"},
+		},
+		{
+			Name:         "custom",
+			Delimiter:    "-",
+			Args:         []string{"-v", "--db-host", "localhost", "--db-tls-cert", "cert.pem", "run", "--db-host", "remote"},
+			ExpectedArgs: []string{"-v", "--db-host", "localhost", "--db-tls-cert", "cert.pem", "run", "--db-host", "remote"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var opts namespaceOptions
+			parser := flags.NewParser(&opts, flags.None)
+			if tt.Delimiter != "" {
+				parser.NamespaceDelimiter = tt.Delimiter
+			}
+			if _, err := parser.ParseArgs(tt.Args); err != nil {
+				t.Fatalf("flags.Parser.ParseArgs() = _, %v; want nil", err)
+			}
+			config := *testConfig
+			config.NamespaceDelimiter = tt.Delimiter
+			config.CommandPath = []string{"run"}
+			args, err := config.Args(opts)
+			testArgsAreEqual(t, tt.ExpectedArgs, args, err)
+		})
+	}
+}
+
+type commaTagOptions struct {
+	Name  string   `long:"name,x"`
+	Value string   `long:"value" default:"a,b"`
+	List  []string `long:"list" default:"c,d" default:"e"`
+}
+
+func TestArgsWithCommaTagValues(t *testing.T) {
+	args, err := UnixConfig.Args(commaTagOptions{Name: "foo", Value: "a,b", List: []string{"c,d", "e"}})
+	testArgsAreEqual(t, []string{"--name,x", "foo"}, args, err)
+	args, err = UnixConfig.Args(commaTagOptions{Value: "a", List: []string{"c"}})
+	testArgsAreEqual(t, []string{"--value", "a", "--list", "c"}, args, err)
+}
+
+func TestParseStructTags(t *testing.T) {
+	tags, err := parseStructTags(`long:"name,omitempty" default:"a,b" default:"" env-delim:","`)
+	if err != nil {
+		t.Fatalf("parseStructTags() = _, %v; want nil", err)
+	}
+	expected := []Tag{{"long", "name,omitempty"}, {"default", "a,b"}, {"default", ""}, {"env-delim", ","}}
+	if !reflect.DeepEqual(tags.tags, expected) {
+		t.Errorf("parseStructTags() = %q; want %q", tags.tags, expected)
+	}
+	for _, tag := range []reflect.StructTag{`long`, `long:name`, `long:"name`, `long:"\x"`} {
+		if _, err = parseStructTags(tag); err == nil {
+			t.Errorf("parseStructTags(%q) = _, nil; want error", tag)
+		}
+	}
+}
+
+func TestArgsWithPrefixes(t *testing.T) {
+	var s struct {
+		DB struct {
+			Host string `long:"host" env:"HOST"`
+			Pool struct {
+				Size int `long:"size"`
+			} `group:"Pool" namespace:"pool"`
+		} `group:"Database" prefix:"db-" env-prefix:"DB__"`
+	}
+	s.DB.Host = "localhost"
+	s.DB.Pool.Size = 2
+	args, err := testConfig.Args(s)
+	testArgsAreEqual(t, []string{"--db-host", "localhost", "--db-pool.size", "2"}, args, err)
+	env, err := testConfig.Env(s)
+	testArgsAreEqual(t, []string{"DB__HOST=localhost"}, env, err)
+}
+
 func testArgsAreEqual(t *testing.T, expected, args []string, err error) {
 	if err != nil {
 		t.Fatalf("Config.Args() = _, %v; want nil", err)
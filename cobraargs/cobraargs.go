@@ -0,0 +1,193 @@
+// Package cobraargs implements a converter from flags of the pflag package
+// (see https://github.com/spf13/pflag) and commands of the cobra package
+// (see https://github.com/spf13/cobra) back to a command-line.
+package cobraargs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sergeymakinen/go-cmdbuilder/v2"
+	"github.com/sergeymakinen/go-cmdbuilder/v2/internal/argv"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// FlagSetArgs converts flags of the provided pflag.FlagSet fs and its remaining
+// positional arguments to command-line arguments using the provided configuration c
+// or UnixConfig with the argument quoter of the current OS, if c is nil.
+//
+// If all is false, only flags that have been changed are converted.
+// Otherwise, every flag which value differs from its default value is converted.
+//
+// Flags having a shorthand are written as short options, unless DisableShortName is true,
+// others as long options. Values of slice flags are written for every element,
+// values of map flags (e.g. pflag.StringToString) are written as a single argument.
+// Short options are never combined, except for count flags (e.g. '-vvv').
+func FlagSetArgs(c *cmdbuilder.Config, fs *pflag.FlagSet, all bool) []string {
+	c = config(c)
+	return syntax(c).AppendPositional(flagSetArgs(c, nil, fs, all, false), fs.Args(), false)
+}
+
+// FlagSetCommandLine converts flags of the provided pflag.FlagSet fs and its remaining
+// positional arguments to a command-line using the provided configuration c
+// or a default configuration, if c is nil (see FlagSetArgs).
+func FlagSetCommandLine(c *cmdbuilder.Config, fs *pflag.FlagSet, all bool) string {
+	c = config(c)
+	args := syntax(c).AppendPositional(flagSetArgs(c, nil, fs, all, true), fs.Args(), true)
+	return strings.Join(args, " ")
+}
+
+// Args converts the path of the provided cobra.Command cmd, starting after
+// the root command, changed flags of every command in the path and positional
+// arguments args to command-line arguments using the provided configuration c
+// or UnixConfig with the argument quoter of the current OS, if c is nil.
+//
+// Flags of a command are written after its name (see FlagSetArgs).
+func Args(c *cmdbuilder.Config, cmd *cobra.Command, args []string) []string {
+	return commandArgs(config(c), cmd, args, false)
+}
+
+// CommandLine converts the path of the provided cobra.Command cmd, changed flags
+// and positional arguments args to a command-line using the provided configuration c
+// or a default configuration, if c is nil (see Args).
+func CommandLine(c *cmdbuilder.Config, cmd *cobra.Command, args []string) string {
+	return strings.Join(commandArgs(config(c), cmd, args, true), " ")
+}
+
+// config returns c or, if c is nil, a copy of UnixConfig as pflag always
+// parses GNU-style flags, quoting arguments the way the OS shell does.
+func config(c *cmdbuilder.Config) *cmdbuilder.Config {
+	if c == nil {
+		c := *cmdbuilder.UnixConfig
+		c.ArgumentQuoter = cmdbuilder.ConfigFor(runtime.GOOS).ArgumentQuoter
+		return &c
+	}
+	return c
+}
+
+func syntax(c *cmdbuilder.Config) *argv.Syntax {
+	s := &argv.Syntax{
+		ShortOptionDelimiter:      c.ShortOptionDelimiter,
+		LongOptionDelimiter:       c.LongOptionDelimiter,
+		OptionArgumentDelimiter:   c.OptionArgumentDelimiter,
+		OptionsTerminator:         c.OptionsTerminator,
+		OptionsTerminatorIfNeeded: c.OptionsTerminatorIfNeeded,
+		ArgumentQuoter:            c.ArgumentQuoter,
+	}
+	if c.SingleDashLongOptions {
+		s.LongOptionDelimiter = c.ShortOptionDelimiter
+	}
+	return s
+}
+
+func commandArgs(c *cmdbuilder.Config, cmd *cobra.Command, positional []string, cmdline bool) []string {
+	var path []*cobra.Command
+	for ; cmd != nil; cmd = cmd.Parent() {
+		path = append([]*cobra.Command{cmd}, path...)
+	}
+	var args []string
+	for i, cmd := range path {
+		if i > 0 {
+			args = append(args, cmd.Name())
+		}
+		args = flagSetArgs(c, args, cmd.LocalFlags(), false, cmdline)
+	}
+	return syntax(c).AppendPositional(args, positional, cmdline)
+}
+
+func flagSetArgs(c *cmdbuilder.Config, args []string, fs *pflag.FlagSet, all, cmdline bool) []string {
+	fs.VisitAll(func(f *pflag.Flag) {
+		if all && f.Value.String() != f.DefValue || !all && f.Changed {
+			args = append(args, flagArgs(c, f, cmdline)...)
+		}
+	})
+	return args
+}
+
+func flagArgs(c *cmdbuilder.Config, f *pflag.Flag, cmdline bool) []string {
+	s := syntax(c)
+	isShort := f.Shorthand != "" && !c.DisableShortName
+	opt := s.OptionName(f.Name, false)
+	if isShort {
+		opt = s.OptionName(f.Shorthand, true)
+	}
+	typ := f.Value.Type()
+	if typ == "count" {
+		n, _ := strconv.Atoi(f.Value.String())
+		if isShort {
+			if n > 0 {
+				return []string{opt + strings.Repeat(f.Shorthand, n-1)}
+			}
+			return []string{opt + "=0"}
+		}
+		if n == 0 {
+			return []string{opt + "=0"}
+		}
+		var args []string
+		for i := 0; i < n; i++ {
+			args = append(args, opt)
+		}
+		return args
+	}
+	var values []string
+	switch v := f.Value.(type) {
+	case pflag.SliceValue:
+		for _, s := range v.GetSlice() {
+			// Most slice flags read their values as CSV records.
+			if typ != "stringArray" && strings.ContainsAny(s, ",\"\n") {
+				s = csvRecord([]string{s})
+			}
+			values = append(values, s)
+		}
+	default:
+		s := v.String()
+		if strings.HasPrefix(typ, "stringTo") {
+			s = sortedMapValue(s)
+		}
+		values = []string{s}
+	}
+	var args []string
+	for _, v := range values {
+		switch {
+		case f.NoOptDefVal != "" && v == f.NoOptDefVal:
+			args = append(args, opt)
+		case f.NoOptDefVal != "":
+			// Flags with optional values take values after '=' only.
+			if cmdline {
+				v = s.Quote(v)
+			}
+			args = append(args, opt+"="+v)
+		default:
+			args = append(args, s.Option(opt, v, false, cmdline)...)
+		}
+	}
+	return args
+}
+
+// sortedMapValue returns the value of a map flag without brackets
+// with records sorted to make the output stable.
+func sortedMapValue(s string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return s
+	}
+	records, err := csv.NewReader(strings.NewReader(s)).Read()
+	if err != nil {
+		return s
+	}
+	sort.Strings(records)
+	return csvRecord(records)
+}
+
+func csvRecord(records []string) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(records)
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
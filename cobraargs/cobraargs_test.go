@@ -0,0 +1,177 @@
+package cobraargs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sergeymakinen/go-cmdbuilder/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newTestFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.BoolP("verbose", "v", false, "")
+	fs.Bool("color", true, "")
+	fs.StringP("name", "n", "", "")
+	fs.CountP("debug", "d", "")
+	fs.Count("level", "")
+	fs.StringSliceP("tag", "t", nil, "")
+	fs.StringArray("file", nil, "")
+	fs.IntSlice("port", []int{80}, "")
+	fs.StringToString("label", nil, "")
+	fs.String("mode", "", "")
+	fs.Lookup("mode").NoOptDefVal = "auto"
+	return fs
+}
+
+func pflagValue(f *pflag.Flag) string {
+	if strings.HasPrefix(f.Value.Type(), "stringTo") {
+		return sortedMapValue(f.Value.String())
+	}
+	return f.Value.String()
+}
+
+func TestFlagSetArgs(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Args         []string
+		All          bool
+		Config       *cmdbuilder.Config
+		ExpectedArgs []string
+		ExpectedCmd  string
+	}{
+		{
+			Name:         "changed",
+			Args:         []string{"-v", "--color=false", "-n", "foo bar", "-ddd", "--level", "--level", "arg"},
+			Config:       cmdbuilder.UnixConfig,
+			ExpectedArgs: []string{"--color=false", "-ddd", "--level", "--level", "-n", "foo bar", "-v", "arg"},
+			ExpectedCmd:  `--color=false -ddd --level --level -n "foo bar" -v arg`,
+		},
+		{
+			Name:         "slices and maps",
+			Args:         []string{"-t", "a,b", "--tag", `c,"d,e"`, "--file", "x,y", "--file", "z", "--label", "b=2,a=1", "--label", `"c=3,4"`},
+			Config:       cmdbuilder.UnixConfig,
+			ExpectedArgs: []string{"--file", "x,y", "--file", "z", "--label", `a=1,b=2,"c=3,4"`, "-t", "a", "-t", "b", "-t", "c", "-t", `"d,e"`},
+			ExpectedCmd:  `--file x,y --file z --label "a=1,b=2,\"c=3,4\"" -t a -t b -t c -t "\"d,e\""`,
+		},
+		{
+			Name:         "optional value",
+			Args:         []string{"--mode", "--", "-arg"},
+			Config:       cmdbuilder.GNUConfig,
+			ExpectedArgs: []string{"--mode", "--", "-arg"},
+			ExpectedCmd:  `--mode -- -arg`,
+		},
+		{
+			Name:         "optional value set",
+			Args:         []string{"--mode=manual mode"},
+			Config:       cmdbuilder.GNUConfig,
+			ExpectedArgs: []string{"--mode=manual mode"},
+			ExpectedCmd:  `--mode='manual mode'`,
+		},
+		{
+			Name:         "all",
+			Args:         []string{"-v", "--color=true", "--port", "80", "-d"},
+			All:          true,
+			Config:       cmdbuilder.UnixConfig,
+			ExpectedArgs: []string{"-d", "-v"},
+			ExpectedCmd:  `-d -v`,
+		},
+		{
+			Name:         "zero count",
+			Args:         []string{"-dd", "--debug=0", "--level", "--level=0"},
+			Config:       cmdbuilder.UnixConfig,
+			ExpectedArgs: []string{"-d=0", "--level=0"},
+			ExpectedCmd:  `-d=0 --level=0`,
+		},
+		{
+			Name:         "nil config",
+			Args:         []string{"-v", "-n", "foo", "--level", "-d"},
+			ExpectedArgs: []string{"-d", "--level", "-n", "foo", "-v"},
+			ExpectedCmd:  `-d --level -n foo -v`,
+		},
+		{
+			Name:         "DisableShortName",
+			Args:         []string{"-v", "-dd", "--port", "80,443"},
+			Config:       &cmdbuilder.Config{DisableShortName: true, LongOptionDelimiter: "--", OptionArgumentDelimiter: "="},
+			ExpectedArgs: []string{"--debug", "--debug", "--port=80", "--port=443", "--verbose"},
+			ExpectedCmd:  `--debug --debug --port=80 --port=443 --verbose`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := newTestFlagSet()
+			if err := fs.Parse(tt.Args); err != nil {
+				t.Fatalf("pflag.FlagSet.Parse() = %v; want nil", err)
+			}
+			args := FlagSetArgs(tt.Config, fs, tt.All)
+			if !reflect.DeepEqual(args, tt.ExpectedArgs) {
+				t.Errorf("FlagSetArgs() = %q; want %q", args, tt.ExpectedArgs)
+			}
+			if cmd := FlagSetCommandLine(tt.Config, fs, tt.All); cmd != tt.ExpectedCmd {
+				t.Errorf("FlagSetCommandLine() = %v; want %v", cmd, tt.ExpectedCmd)
+			}
+
+			parsed := newTestFlagSet()
+			if err := parsed.Parse(args); err != nil {
+				t.Fatalf("pflag.FlagSet.Parse() = %v; want nil", err)
+			}
+			fs.VisitAll(func(f *pflag.Flag) {
+				if v := pflagValue(parsed.Lookup(f.Name)); v != pflagValue(f) {
+					t.Errorf("flag %s = %q; want %q", f.Name, v, pflagValue(f))
+				}
+			})
+			if !reflect.DeepEqual(parsed.Args(), fs.Args()) {
+				t.Errorf("pflag.FlagSet.Args() = %q; want %q", parsed.Args(), fs.Args())
+			}
+		})
+	}
+}
+
+func newTestCommand(run func(cmd *cobra.Command, args []string)) *cobra.Command {
+	root := &cobra.Command{Use: "app"}
+	root.PersistentFlags().BoolP("verbose", "v", false, "")
+	remote := &cobra.Command{Use: "remote"}
+	remote.Flags().String("config", "", "")
+	add := &cobra.Command{Use: "add", Run: run}
+	add.Flags().StringSliceP("track", "t", nil, "")
+	add.Flags().Bool("mirror", false, "")
+	remote.AddCommand(add)
+	root.AddCommand(remote)
+	return root
+}
+
+func TestArgs(t *testing.T) {
+	var (
+		args []string
+		cmd  string
+	)
+	root := newTestCommand(func(c *cobra.Command, positional []string) {
+		args = Args(cmdbuilder.UnixConfig, c, positional)
+		cmd = CommandLine(cmdbuilder.UnixConfig, c, positional)
+	})
+	root.SetArgs([]string{"remote", "add", "-v", "--mirror", "-t", "main,dev", "origin", "git@example.com:repo"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("cobra.Command.Execute() = %v; want nil", err)
+	}
+	expectedArgs := []string{"-v", "remote", "add", "--mirror", "-t", "main", "-t", "dev", "origin", "git@example.com:repo"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Args() = %q; want %q", args, expectedArgs)
+	}
+	if expectedCmd := "-v remote add --mirror -t main -t dev origin git@example.com:repo"; cmd != expectedCmd {
+		t.Errorf("CommandLine() = %v; want %v", cmd, expectedCmd)
+	}
+
+	var reparsed []string
+	root = newTestCommand(func(c *cobra.Command, positional []string) {
+		reparsed = Args(cmdbuilder.UnixConfig, c, positional)
+	})
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		t.Fatalf("cobra.Command.Execute() = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(reparsed, args) {
+		t.Errorf("Args() = %q; want %q", reparsed, args)
+	}
+}
@@ -0,0 +1,80 @@
+package cmdbuilder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Command returns the exec.Cmd struct to execute the named program
+// with command-line arguments converted from the provided struct
+// (or pointer to a struct) v defining command-line options and their values
+// using the provided configuration c (see Config.Args).
+//
+// If some options are passed as environment variables (see Config.Env),
+// the environment of the command is set to the environment
+// of the current process with the variables appended.
+// Variables of such options that are not set by v (e.g. having default values)
+// are removed from the inherited environment, so the command doesn't pick them up.
+func (c *Config) Command(ctx context.Context, name string, v interface{}) (*exec.Cmd, error) {
+	parsed, err := c.parse(v)
+	if err != nil {
+		return nil, err
+	}
+	args, err := c.buildArgs(parsed, false)
+	if err != nil {
+		return nil, err
+	}
+	env, err := c.env(parsed)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = commandEnv(parsed, env)
+	return cmd, nil
+}
+
+// commandEnv returns the environment of the current process
+// without variables of options passed as environment variables
+// followed by env or nil, if there are no such options.
+func commandEnv(parsed []arg, env []string) []string {
+	names := make(map[string]bool)
+	for _, arg := range parsed {
+		if arg.IsEnv() {
+			names[envKey(arg.EnvName())] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	var environ []string
+	for _, kv := range os.Environ() {
+		name := kv
+		if i := strings.Index(kv, "="); i > 0 {
+			name = kv[:i]
+		}
+		if !names[envKey(name)] {
+			environ = append(environ, kv)
+		}
+	}
+	return append(environ, env...)
+}
+
+// envKey returns the environment variable name in a comparable form
+// as names are case-insensitive on Windows.
+func envKey(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// Command returns the exec.Cmd struct to execute the named program
+// with command-line arguments converted from the provided struct
+// (or pointer to a struct) v defining command-line options and their values
+// using a default configuration (see Config.Command).
+func Command(ctx context.Context, name string, v interface{}) (*exec.Cmd, error) {
+	return defaultConfig.Command(ctx, name, v)
+}
@@ -0,0 +1,87 @@
+package cmdbuilder
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	opts := envOptions{
+		Token:   "secret",
+		Verbose: true,
+	}
+	cmd, err := testConfig.Command(context.Background(), "true", opts)
+	if err != nil {
+		t.Fatalf("Config.Command() = _, %v; want nil", err)
+	}
+	expected := []string{"true", "-v"}
+	if !reflect.DeepEqual(cmd.Args, expected) {
+		t.Errorf("Config.Command().Args = %v; want %v", cmd.Args, expected)
+	}
+	if len(cmd.Env) == 0 || cmd.Env[len(cmd.Env)-1] != "TOKEN=secret" {
+		t.Errorf("Config.Command().Env = %v; want ending with %q", cmd.Env, "TOKEN=secret")
+	}
+
+	cmd, err = testConfig.Command(context.Background(), "true", struct {
+		Name string `long:"name"`
+	}{
+		Name: "foo bar",
+	})
+	if err != nil {
+		t.Fatalf("Config.Command() = _, %v; want nil", err)
+	}
+	expected = []string{"true", "--name", "foo bar"}
+	if !reflect.DeepEqual(cmd.Args, expected) {
+		t.Errorf("Config.Command().Args = %v; want %v", cmd.Args, expected)
+	}
+	if cmd.Env != nil {
+		t.Errorf("Config.Command().Env = %v; want nil", cmd.Env)
+	}
+
+	_, err = testConfig.Command(context.Background(), "true", struct {
+		Value marshalTest `long:"value"`
+	}{
+		Value: "fail",
+	})
+	if _, ok := err.(*FieldError); !ok {
+		t.Errorf("Config.Command() = _, %v; want *FieldError", err)
+	}
+}
+
+func TestCommandWithInheritedEnv(t *testing.T) {
+	for k, v := range map[string]string{"UNSET": "inherited", "CMDBUILDER_TEST": "kept"} {
+		old, ok := os.LookupEnv(k)
+		os.Setenv(k, v)
+		defer func(k string) {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k)
+	}
+	cmd, err := testConfig.Command(context.Background(), "true", envOptions{Token: "secret"})
+	if err != nil {
+		t.Fatalf("Config.Command() = _, %v; want nil", err)
+	}
+	var unset, kept bool
+	for _, kv := range cmd.Env {
+		switch kv {
+		case "UNSET=inherited":
+			unset = true
+		case "CMDBUILDER_TEST=kept":
+			kept = true
+		}
+	}
+	if unset {
+		t.Errorf("Config.Command().Env = %v; want without %q", cmd.Env, "UNSET=inherited")
+	}
+	if !kept {
+		t.Errorf("Config.Command().Env = %v; want with %q", cmd.Env, "CMDBUILDER_TEST=kept")
+	}
+	if cmd.Env[len(cmd.Env)-1] != "TOKEN=secret" {
+		t.Errorf("Config.Command().Env = %v; want ending with %q", cmd.Env, "TOKEN=secret")
+	}
+}
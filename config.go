@@ -0,0 +1,110 @@
+package cmdbuilder
+
+import (
+	"runtime"
+
+	"github.com/sergeymakinen/go-quote/unix"
+	"github.com/sergeymakinen/go-quote/windows"
+)
+
+// UnixConfig follows the conventions of Unix-like systems
+// and quotes arguments for POSIX shells using double quotes, if needed.
+var UnixConfig = &Config{
+	ShortOptionDelimiter:            "-",
+	LongOptionDelimiter:             "--",
+	OptionArgumentDelimiter:         " ",
+	OptionOptionalArgumentDelimiter: "=",
+	ArgumentQuoter:                  quoteUnix,
+}
+
+// GNUConfig follows the GNU conventions: in addition to UnixConfig,
+// it writes '--' before positional arguments beginning with a hyphen
+// and quotes arguments for POSIX shells using single quotes, if needed.
+var GNUConfig = &Config{
+	ShortOptionDelimiter:            "-",
+	LongOptionDelimiter:             "--",
+	OptionArgumentDelimiter:         " ",
+	OptionOptionalArgumentDelimiter: "=",
+	OptionsTerminator:               "--",
+	OptionsTerminatorIfNeeded:       true,
+	ArgumentQuoter:                  quoteGNU,
+}
+
+// WindowsConfig follows the conventions of Windows
+// and quotes arguments as parsed by CommandLineToArgvW, if needed.
+var WindowsConfig = &Config{
+	DisableCombiningShortOptions:    true,
+	ShortOptionDelimiter:            "/",
+	LongOptionDelimiter:             "/",
+	OptionArgumentDelimiter:         " ",
+	OptionOptionalArgumentDelimiter: ":",
+	OptionsTerminator:               "",
+	ArgumentQuoter:                  quoteWindows,
+}
+
+// GoConfig follows the conventions of the flag package:
+// both short and long options are written with a single hyphen
+// and separated from their arguments with '='.
+// Arguments are quoted as in UnixConfig.
+var GoConfig = &Config{
+	DisableCombiningShortOptions:    true,
+	ShortOptionDelimiter:            "-",
+	LongOptionDelimiter:             "-",
+	SingleDashLongOptions:           true,
+	OptionArgumentDelimiter:         "=",
+	OptionOptionalArgumentDelimiter: "=",
+	OptionsTerminator:               "--",
+	OptionsTerminatorIfNeeded:       true,
+	ArgumentQuoter:                  quoteUnix,
+}
+
+// KongConfig follows the conventions of the kong package
+// (see https://github.com/alecthomas/kong): it reads struct field tags
+// using KongDialect and writes '--' before positional arguments beginning with a hyphen.
+// Arguments are quoted as in UnixConfig.
+var KongConfig = &Config{
+	ShortOptionDelimiter:            "-",
+	LongOptionDelimiter:             "--",
+	OptionArgumentDelimiter:         " ",
+	OptionOptionalArgumentDelimiter: "=",
+	OptionsTerminator:               "--",
+	OptionsTerminatorIfNeeded:       true,
+	TagDialect:                      KongDialect,
+	ArgumentQuoter:                  quoteUnix,
+}
+
+var defaultConfig = ConfigFor(runtime.GOOS)
+
+// ConfigFor returns a copy of the preset following the conventions
+// of the goos operating system (as in runtime.GOOS):
+// WindowsConfig for "windows" and UnixConfig otherwise.
+func ConfigFor(goos string) *Config {
+	var c Config
+	if goos == "windows" {
+		c = *WindowsConfig
+	} else {
+		c = *UnixConfig
+	}
+	return &c
+}
+
+func quoteUnix(s string) string {
+	if unix.DoubleQuote.MustQuote(s) {
+		return unix.DoubleQuote.Quote(s)
+	}
+	return s
+}
+
+func quoteGNU(s string) string {
+	if unix.SingleQuote.MustQuote(s) {
+		return unix.SingleQuote.Quote(s)
+	}
+	return s
+}
+
+func quoteWindows(s string) string {
+	if windows.Argv.MustQuote(s) {
+		return windows.Argv.Quote(s)
+	}
+	return s
+}
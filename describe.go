@@ -0,0 +1,104 @@
+package cmdbuilder
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// ArgKind specifies what a struct field defines.
+type ArgKind int
+
+const (
+	OptionArg     ArgKind = iota // an option
+	PositionalArg                // a positional argument
+	CommandArg                   // a command
+)
+
+// Option describes an option, a positional argument or an active command
+// defined by a struct field as seen when converting the struct.
+type Option struct {
+	Kind       ArgKind
+	Path       []string            // names of the struct fields from the root struct to the field
+	Struct     reflect.Type        // type of the struct containing the field
+	Field      reflect.StructField // struct field
+	Name       string              // long name written (including namespaces) or name of the command
+	ShortName  string              // short name written, if any
+	Names      []string            // all long names (including namespaces and the name valid for the target version) or name and aliases of the command
+	ShortNames []string            // all short names
+	EnvName    string              // name of the environment variable, if any
+	Value      []string            // value converted to strings (the number for count options)
+	Default    []string            // default value converted to strings (the number for count options)
+	Optional   bool                // whether the value of the option is optional
+	Provided   bool                // whether the value differs from the default value
+	Args       []string            // arguments written, as if short options are not combined
+	Emitted    bool                // whether the arguments are written
+	Reason     string              // why the arguments are written or not
+}
+
+// Describe returns descriptions of options, positional arguments and active commands
+// defined by the provided struct (or pointer to a struct) v
+// in order of the fields using the provided configuration c.
+//
+// Options and positional arguments unsupported by Config.TargetVersion
+// are also described, while constraints are not checked (see Check).
+func (c *Config) Describe(v interface{}) ([]Option, error) {
+	parsed, err := c.parseAll(v, true)
+	if err != nil {
+		return nil, err
+	}
+	separate := *c
+	separate.DisableCombiningShortOptions = true
+	opts := make([]Option, 0, len(parsed))
+	for _, a := range parsed {
+		opt := Option{
+			Kind:       a.kind,
+			Path:       a.path,
+			Struct:     a.Struct(),
+			Field:      a.Field(),
+			Name:       a.Name(),
+			ShortName:  a.ShortName(),
+			Names:      a.Names(),
+			ShortNames: a.ShortNames(),
+			EnvName:    a.EnvName(),
+			Value:      a.Value(),
+			Default:    a.def,
+			Optional:   a.IsValueOptional(),
+			Provided:   a.IsProvided(),
+		}
+		switch {
+		case a.IsCommand():
+			opt.Names = command{tags: a.tags}.Names()
+			opt.Args = []string{a.Name()}
+			opt.Emitted, opt.Reason = true, "command is active"
+		case a.unsupported != "":
+			opt.Reason = a.unsupported
+		case !a.IsProvided() && a.IsPositional():
+			opt.Reason = "positional argument does not have value"
+		case !a.IsProvided():
+			opt.Reason = "option has default value"
+		case a.IsPositional():
+			opt.Args = a.Value()
+			opt.Emitted, opt.Reason = true, "positional argument has value"
+		case !a.IsFlag():
+			opt.Reason = "option is passed as environment variable " + a.EnvName()
+		default:
+			if opt.Args, err = separate.options([]arg{a}, false); err != nil {
+				return nil, err
+			}
+			opt.Emitted, opt.Reason = true, "option has non-default value"
+		}
+		if a.isCount() {
+			opt.Value = []string{strconv.Itoa(len(opt.Value))}
+			opt.Default = []string{strconv.Itoa(len(opt.Default))}
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
+
+// Describe returns descriptions of options, positional arguments and active commands
+// defined by the provided struct (or pointer to a struct) v
+// using a default configuration (see Config.Describe).
+func Describe(v interface{}) ([]Option, error) {
+	return defaultConfig.Describe(v)
+}
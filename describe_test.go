@@ -0,0 +1,77 @@
+package cmdbuilder
+
+import (
+	"reflect"
+	"testing"
+)
+
+type describeRunCommand struct {
+	Args struct {
+		Script string
+	} `positional-args:"true"`
+}
+
+type describeOptions struct {
+	Verbose int    `short:"v" long:"verbose" count:"true"`
+	Color   bool   `long:"color" long:"colour" default:"true" negate:"--no-color"`
+	Token   string `long:"token" env:"TOKEN" delivery:"env"`
+	Jobs    int    `short:"j" long:"jobs" since:"2.0"`
+	Level   string `long:"level" default:"info"`
+	Output  string `long:"output" renamed-from:"out@2.0"`
+	DB      struct {
+		Host string `long:"host"`
+	} `group:"Database" namespace:"db"`
+	Run *describeRunCommand `command:"run" alias:"r"`
+}
+
+func TestDescribe(t *testing.T) {
+	opts := describeOptions{Verbose: 2, Token: "secret", Jobs: 4, Level: "info", Output: "file"}
+	opts.DB.Host = "localhost"
+	opts.Run = &describeRunCommand{}
+	opts.Run.Args.Script = "build"
+	c := *UnixConfig
+	c.TargetVersion = "1.0"
+	c.DropUnsupported = true
+	described, err := c.Describe(opts)
+	if err != nil {
+		t.Fatalf("Config.Describe() = _, %v; want nil", err)
+	}
+	st := reflect.TypeOf(opts)
+	expected := []struct {
+		Kind    ArgKind
+		Path    []string
+		Names   []string
+		Args    []string
+		Emitted bool
+		Reason  string
+	}{
+		{OptionArg, []string{"Verbose"}, []string{"verbose"}, []string{"-v", "-v"}, true, "option has non-default value"},
+		{OptionArg, []string{"Color"}, []string{"color", "colour"}, []string{"--no-color"}, true, "option has non-default value"},
+		{OptionArg, []string{"Token"}, []string{"token"}, nil, false, "option is passed as environment variable TOKEN"},
+		{OptionArg, []string{"Jobs"}, []string{"jobs"}, nil, false, "option is added in version 2.0, target version is 1.0"},
+		{OptionArg, []string{"Level"}, []string{"level"}, nil, false, "option has default value"},
+		{OptionArg, []string{"Output"}, []string{"output", "out"}, []string{"--out", "file"}, true, "option has non-default value"},
+		{OptionArg, []string{"DB", "Host"}, []string{"db.host"}, []string{"--db.host", "localhost"}, true, "option has non-default value"},
+		{CommandArg, []string{"Run"}, []string{"run", "r"}, []string{"run"}, true, "command is active"},
+		{PositionalArg, []string{"Run", "Args", "Script"}, nil, []string{"build"}, true, "positional argument has value"},
+	}
+	if len(described) != len(expected) {
+		t.Fatalf("len(Config.Describe()) = %d; want %d", len(described), len(expected))
+	}
+	for i, opt := range described {
+		e := expected[i]
+		if opt.Kind != e.Kind || !reflect.DeepEqual(opt.Path, e.Path) || !reflect.DeepEqual(opt.Names, e.Names) ||
+			!reflect.DeepEqual(opt.Args, e.Args) || opt.Emitted != e.Emitted || opt.Reason != e.Reason {
+			t.Errorf("Config.Describe()[%d] = %d %q %q %q %v %q; want %d %q %q %q %v %q", i,
+				opt.Kind, opt.Path, opt.Names, opt.Args, opt.Emitted, opt.Reason,
+				e.Kind, e.Path, e.Names, e.Args, e.Emitted, e.Reason)
+		}
+	}
+	if opt := described[0]; opt.Struct != st || opt.Field.Name != "Verbose" || opt.ShortName != "v" ||
+		!reflect.DeepEqual(opt.Value, []string{"2"}) || !reflect.DeepEqual(opt.Default, []string{"0"}) || !opt.Optional || !opt.Provided {
+		t.Errorf("Config.Describe()[0] = %+v", opt)
+	}
+
+	args, err := c.Args(opts)
+	testArgsAreEqual(t, []string{"-vv", "--no-color", "--out", "file", "--db.host", "localhost", "run", "build"}, args, err)
+}
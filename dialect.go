@@ -0,0 +1,274 @@
+package cmdbuilder
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Tag represents a struct field tag in the notation of the flags package
+// (see Supported field tags).
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// TagDialect returns tags of the struct field sf in the notation of the flags package,
+// so structs defined for other command-line parsers may be converted.
+// A key may appear multiple times.
+type TagDialect func(sf reflect.StructField) ([]Tag, error)
+
+// FlagsDialect returns tags of the struct field sf as is.
+func FlagsDialect(sf reflect.StructField) ([]Tag, error) {
+	tags, err := parseStructTags(sf.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return tags.tags, nil
+}
+
+// TagKeyDialect returns a TagDialect reading tags of a struct field only from the single key
+// in the form "long=name,short=n,choice=a,choice='b,c'", ignoring other keys,
+// so the struct may have tags of other packages, e.g. `cmd:"long=name" default:"foo"`.
+// Keys may be repeated and values containing commas are enclosed in single quotes.
+// Keys without '=' are "true", while "key=" defines the empty value.
+// A field with the "-" value is ignored.
+func TagKeyDialect(key string) TagDialect {
+	return func(sf reflect.StructField) ([]Tag, error) {
+		raw, err := parseStructTags(sf.Tag)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := lookupTag(raw.tags, key)
+		if !ok {
+			return nil, nil
+		}
+		if s == "-" {
+			return []Tag{{Key: "no-flag", Value: "true"}}, nil
+		}
+		tags, err := parseTagList(s, "true")
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad syntax for struct tag value %q", key)
+		}
+		return tags, nil
+	}
+}
+
+// TagPrefixDialect returns a TagDialect reading only tags of a struct field which keys
+// begin with prefix, e.g. `cmd-long:"name"` for the "cmd-" prefix, ignoring other keys.
+func TagPrefixDialect(prefix string) TagDialect {
+	return func(sf reflect.StructField) ([]Tag, error) {
+		raw, err := parseStructTags(sf.Tag)
+		if err != nil {
+			return nil, err
+		}
+		var tags []Tag
+		for _, tag := range raw.tags {
+			if strings.HasPrefix(tag.Key, prefix) {
+				tags = append(tags, Tag{Key: strings.TrimPrefix(tag.Key, prefix), Value: tag.Value})
+			}
+		}
+		return tags, nil
+	}
+}
+
+// KongDialect translates tags of the struct field sf defined for the kong package
+// (see https://github.com/alecthomas/kong). Tags may also be specified
+// in the single kong tag, e.g. `kong:"name='verbose',short='v'"`.
+//
+// As in the kong package, every exported field is an option, unless it has
+// the cmd, arg or embed tag (or is an embedded struct), and its long name is
+// the field name in kebab case, unless it has the name tag.
+// Values of slice options are joined with ',' and values of map options
+// are joined with ';', unless the option has the sep or mapsep tag respectively.
+// Negatable options are turned off with the '--no-' prefix.
+//
+// Prefixes of embedded structs are written before long names
+// and environment variable names as is.
+//
+// Commands are written only if listed in Config.CommandPath, as kong commands
+// are usually defined by structs.
+func KongDialect(sf reflect.StructField) ([]Tag, error) {
+	raw, err := parseStructTags(sf.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := lookupTag(raw.tags, "kong"); ok {
+		if s == "-" {
+			return []Tag{{Key: "no-flag", Value: "true"}}, nil
+		}
+		list, err := parseTagList(s, "")
+		if err != nil {
+			return nil, errors.Wrap(err, "bad syntax for struct tag value \"kong\"")
+		}
+		raw.tags = append(raw.tags, list...)
+	}
+	get := func(key string) string {
+		s, _ := lookupTag(raw.tags, key)
+		return s
+	}
+	has := func(key string) bool {
+		_, ok := lookupTag(raw.tags, key)
+		return ok
+	}
+	name := get("name")
+	if name == "" {
+		name = KebabCase(sf.Name)
+	}
+	var tags []Tag
+	add := func(key string, values ...string) {
+		for _, v := range values {
+			tags = append(tags, Tag{Key: key, Value: v})
+		}
+	}
+	switch {
+	case has("cmd"):
+		add("command", name)
+		add("alias", splitTagValue(get("aliases"), ",")...)
+		return tags, nil
+	case has("embed") || sf.Anonymous && indirectType(sf.Type).Kind() == reflect.Struct:
+		add("group", "true")
+		if prefix := get("prefix"); prefix != "" {
+			add("prefix", prefix)
+		}
+		if prefix := get("envprefix"); prefix != "" {
+			add("env-prefix", prefix)
+		}
+		return tags, nil
+	case has("arg"):
+		add("positional", "true")
+		if !has("optional") && !has("default") {
+			add("required", "true")
+		}
+	default:
+		add("long", name)
+		add("long", splitTagValue(get("aliases"), ",")...)
+		if short := get("short"); short != "" {
+			add("short", short)
+		}
+		if has("required") {
+			add("required", "true")
+		}
+		if has("negatable") {
+			add("negation-prefix", "no-")
+		}
+		if get("type") == "counter" {
+			add("count", "true")
+		}
+	}
+	sep, mapSep := ",", ";"
+	if has("sep") {
+		sep = get("sep")
+	}
+	if has("mapsep") {
+		mapSep = get("mapsep")
+	}
+	isArg := has("arg")
+	switch indirectType(sf.Type).Kind() {
+	case reflect.Slice, reflect.Array:
+		if !isArg {
+			add("join", sep)
+		}
+		if s := get("default"); s != "" {
+			add("default", splitTagValue(s, sep)...)
+		}
+	case reflect.Map:
+		if !isArg {
+			add("join", mapSep)
+		}
+		add("key-value-delimiter", "=")
+		if s := get("default"); s != "" {
+			add("default", splitTagValue(s, mapSep)...)
+		}
+	default:
+		if has("default") {
+			add("default", get("default"))
+		}
+	}
+	if enum := get("enum"); enum != "" {
+		for _, s := range splitTagValue(enum, ",") {
+			add("choice", strings.TrimSpace(s))
+		}
+	}
+	if env := get("env"); env != "" {
+		add("env", splitTagValue(env, ",")[0])
+	}
+	return tags, nil
+}
+
+func lookupTag(tags []Tag, key string) (string, bool) {
+	for _, tag := range tags {
+		if tag.Key == key {
+			return tag.Value, true
+		}
+	}
+	return "", false
+}
+
+// splitTagValue splits s by sep, unless sep is empty or "none".
+func splitTagValue(s, sep string) []string {
+	if s == "" {
+		return nil
+	}
+	if sep == "" || sep == "none" {
+		return []string{s}
+	}
+	return strings.Split(s, sep)
+}
+
+// parseTagList parses s in the form "key1,key2=value,key3='value,with,commas',key4=".
+// Keys without '=' have the bare value, while keys followed by '=' may have empty values.
+func parseTagList(s, bare string) ([]Tag, error) {
+	var (
+		tags   []Tag
+		buf    strings.Builder
+		key    string
+		hasKey bool
+		quoted bool
+	)
+	flush := func() {
+		if hasKey {
+			tags = append(tags, Tag{Key: key, Value: buf.String()})
+		} else if k := strings.TrimSpace(buf.String()); k != "" {
+			tags = append(tags, Tag{Key: k, Value: bare})
+		}
+		buf.Reset()
+		key, hasKey = "", false
+	}
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\' && quoted && i+1 < len(s):
+			i++
+			buf.WriteByte(s[i])
+		case ch == '\'' && hasKey:
+			quoted = !quoted
+		case quoted:
+			buf.WriteByte(ch)
+		case ch == '=' && !hasKey:
+			key, hasKey = strings.TrimSpace(buf.String()), true
+			buf.Reset()
+		case ch == ',':
+			flush()
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	flush()
+	return tags, nil
+}
+
+func (c *Config) fieldTags(sf reflect.StructField) (*structTags, error) {
+	dialect := c.TagDialect
+	if dialect == nil {
+		dialect = FlagsDialect
+	}
+	tags, err := dialect(sf)
+	if err != nil {
+		return nil, err
+	}
+	return &structTags{tags: tags}, nil
+}
@@ -0,0 +1,184 @@
+package cmdbuilder
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+type kongServer struct {
+	Host string `default:"localhost"`
+	Port int    `default:"8080"`
+}
+
+type kongOptions struct {
+	Verbose   int    `short:"v" type:"counter"`
+	Color     bool   `negatable:"" default:"true"`
+	Name      string `kong:"name='user-name',short='n'"`
+	HTTPProxy string
+	Tags      []string
+	Files     []string `sep:"none"`
+	Labels    map[string]string
+	Level     string     `enum:"debug,info,warn" default:"info"`
+	Animals   []string   `enum:"cat,dog" default:"cat"`
+	Server    kongServer `embed:"" prefix:"server-"`
+	DB        kongServer `embed:"" prefix:"db."`
+	Ignored   string     `kong:"-"`
+	Remote    struct {
+		Add struct {
+			Mirror bool
+			Name   string   `arg:""`
+			URLs   []string `arg:"" optional:""`
+		} `cmd:"" aliases:"a"`
+	} `cmd:""`
+}
+
+func TestKongDialect(t *testing.T) {
+	opts := kongOptions{
+		Verbose:   3,
+		Color:     false,
+		Name:      "foo bar",
+		HTTPProxy: "http://proxy",
+		Tags:      []string{"a", "b"},
+		Files:     []string{"x,y", "z"},
+		Labels:    map[string]string{"b": "2", "a": "1"},
+		Level:     "warn",
+		Animals:   []string{"cat", "dog"},
+		Server:    kongServer{Host: "example.com", Port: 8080},
+		DB:        kongServer{Host: "db.example.com", Port: 8080},
+		Ignored:   "ignored",
+	}
+	opts.Remote.Add.Mirror = true
+	opts.Remote.Add.Name = "origin"
+	opts.Remote.Add.URLs = []string{"u1", "-u2"}
+	c := *KongConfig
+	c.CommandPath = []string{"remote", "a"}
+	c.Validate = true
+	expected := []string{
+		"-vvv", "--no-color", "-n", "foo bar", "--http-proxy", "http://proxy",
+		"--tags", "a,b", "--files", "x,y", "--files", "z", "--labels", "a=1;b=2",
+		"--level", "warn", "--animals", "cat,dog", "--server-host", "example.com", "--db.host", "db.example.com",
+		"remote", "add", "--mirror", "--", "origin", "u1", "-u2",
+	}
+	args, err := c.Args(opts)
+	testArgsAreEqual(t, expected, args, err)
+	cmdLine, err := c.CommandLine(opts)
+	testCmdLineIsEqual(t, `-vvv --no-color -n "foo bar" --http-proxy http://proxy --tags a,b --files x,y --files z --labels "a=1;b=2" --level warn --animals cat,dog --server-host example.com --db.host db.example.com remote add --mirror -- origin u1 -u2`, cmdLine, err)
+
+	var parsed kongOptions
+	parser, err := kong.New(&parsed)
+	if err != nil {
+		t.Fatalf("kong.New() = _, %v; want nil", err)
+	}
+	ctx, err := parser.Parse(expected)
+	if err != nil {
+		t.Fatalf("kong.Kong.Parse() = _, %v; want nil", err)
+	}
+	if ctx.Command() != "remote add <name> <ur-ls>" {
+		t.Errorf("kong.Context.Command() = %q; want %q", ctx.Command(), "remote add <name> <ur-ls>")
+	}
+	opts.Ignored = ""
+	if !reflect.DeepEqual(parsed, opts) {
+		t.Errorf("kong.Kong.Parse() = %+v; want %+v", parsed, opts)
+	}
+
+	opts.Remote.Add.Name = ""
+	_, err = c.Args(opts)
+	testFieldErrorsAre(t, err, ErrRequired)
+	opts.Remote.Add.Name = "origin"
+	opts.Level = "error"
+	_, err = c.Args(opts)
+	testFieldErrorsAre(t, err, ErrInvalidChoice)
+}
+
+func testFieldErrorsAre(t *testing.T, err, target error) {
+	t.Helper()
+	errs, ok := err.(FieldErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("Config.Args() = _, %v; want FieldErrors with 1 error", err)
+	}
+	if !errors.Is(errs[0], target) {
+		t.Errorf("errors.Is(%v, %v) = false; want true", errs[0], target)
+	}
+}
+
+type sharedOptions struct {
+	Name    string   `cmd:"long=name,short=n" default:"foo"`
+	Tags    []string `cmd:"long=tag,default=a,default=b,join=','"`
+	Force   bool     `cmd:"short=f,required"`
+	Ignored string   `cmd:"-" long:"ignored"`
+	Other   string   `long:"other"`
+}
+
+type prefixedOptions struct {
+	Name  string `cmd-long:"name" cmd-short:"n" default:"foo"`
+	Level int    `cmd-long:"level" cmd-default:"1" default:"2"`
+	Other string `long:"other"`
+}
+
+func TestTagKeyDialect(t *testing.T) {
+	c := *UnixConfig
+	c.TagDialect = TagKeyDialect("cmd")
+	c.Validate = true
+	opts := sharedOptions{
+		Name:    "foo",
+		Tags:    []string{"a", "b"},
+		Force:   true,
+		Ignored: "bar",
+		Other:   "baz",
+	}
+	args, err := c.Args(opts)
+	testArgsAreEqual(t, []string{"-f", "-n", "foo"}, args, err)
+	opts.Force = false
+	_, err = c.Args(opts)
+	testFieldErrorsAre(t, err, ErrRequired)
+
+	type badOptions struct {
+		Name string `cmd:"long='name"`
+	}
+	if _, err = c.Args(badOptions{}); err == nil {
+		t.Errorf("Config.Args() = _, nil; want error")
+	}
+}
+
+func TestTagKeyDialectWithEmptyValues(t *testing.T) {
+	c := *UnixConfig
+	c.TagDialect = TagKeyDialect("cmd")
+	s := struct {
+		Name  string `cmd:"long=name,default="`
+		Level string `cmd:"long=level,optional,optional-value=info"`
+	}{
+		Name:  "true",
+		Level: "info",
+	}
+	args, err := c.Args(s)
+	testArgsAreEqual(t, []string{"--name", "true", "--level"}, args, err)
+	s.Name = ""
+	args, err = c.Args(s)
+	testArgsAreEqual(t, []string{"--level"}, args, err)
+}
+
+func TestTagPrefixDialect(t *testing.T) {
+	c := *UnixConfig
+	c.TagDialect = TagPrefixDialect("cmd-")
+	args, err := c.Args(prefixedOptions{Name: "foo", Level: 2, Other: "baz"})
+	testArgsAreEqual(t, []string{"-n", "foo", "--level", "2"}, args, err)
+	args, err = c.Args(prefixedOptions{Level: 1})
+	testArgsAreEqual(t, nil, args, err)
+}
+
+func TestParseTagList(t *testing.T) {
+	tags, err := parseTagList(`long=name,optional,default=,choice='a,b',help='it\'s'`, "true")
+	if err != nil {
+		t.Fatalf("parseTagList() = _, %v; want nil", err)
+	}
+	expected := []Tag{{"long", "name"}, {"optional", "true"}, {"default", ""}, {"choice", "a,b"}, {"help", "it's"}}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("parseTagList() = %q; want %q", tags, expected)
+	}
+	if _, err = parseTagList(`long='name`, "true"); err == nil {
+		t.Errorf("parseTagList() = _, nil; want error")
+	}
+}
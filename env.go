@@ -0,0 +1,90 @@
+package cmdbuilder
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Delivery specifies how an option having the env tag is passed to a command.
+type Delivery int
+
+const (
+	DeliverBoth Delivery = iota // as a command-line argument and an environment variable
+	DeliverFlag                 // as a command-line argument only
+	DeliverEnv                  // as an environment variable only
+)
+
+func (c *Config) delivery(tags *structTags) (Delivery, error) {
+	switch s := tags.First("delivery"); s {
+	case "":
+		return c.Delivery, nil
+	case "both":
+		return DeliverBoth, nil
+	case "flag":
+		return DeliverFlag, nil
+	case "env":
+		return DeliverEnv, nil
+	default:
+		return 0, errors.Errorf("unknown delivery %q", s)
+	}
+}
+
+func (c *Config) envNamespaceDelimiter() string {
+	if c.EnvNamespaceDelimiter == "" {
+		return "_"
+	}
+	return c.EnvNamespaceDelimiter
+}
+
+// Env converts the provided struct (or pointer to a struct) v
+// defining command-line options and their values to environment variables
+// in the form 'key=value' using the provided configuration c.
+//
+// Only options having the env tag and passed as environment variables
+// (see Config.Delivery) are converted. Values of slice and map options
+// are joined with the delimiter from the env-delim tag.
+func (c *Config) Env(v interface{}) ([]string, error) {
+	parsed, err := c.parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.env(parsed)
+}
+
+func (c *Config) env(parsed []arg) ([]string, error) {
+	var env []string
+	for _, arg := range parsed {
+		if !arg.IsEnv() || !arg.IsProvided() {
+			continue
+		}
+		values := arg.Value()
+		if arg.isCount() {
+			values = []string{strconv.Itoa(len(values))}
+		}
+		delim := arg.tags.First("env-delim")
+		if len(values) > 1 {
+			if delim == "" {
+				return nil, arg.error(errors.New("option with multiple values does not have env-delim"))
+			}
+			for i, v := range values {
+				if strings.Contains(v, delim) {
+					return nil, arg.error(&elemError{
+						index: i,
+						err:   errors.Errorf("value contains env-delim %q", delim),
+					})
+				}
+			}
+		}
+		env = append(env, arg.EnvName()+"="+strings.Join(values, delim))
+	}
+	return env, nil
+}
+
+// Env converts the provided struct (or pointer to a struct) v
+// defining command-line options and their values to environment variables
+// in the form 'key=value' using a default configuration.
+func Env(v interface{}) ([]string, error) {
+	return defaultConfig.Env(v)
+}
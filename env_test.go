@@ -0,0 +1,104 @@
+package cmdbuilder
+
+import (
+	"strings"
+	"testing"
+)
+
+type envOptions struct {
+	Token   string            `long:"token" env:"TOKEN" delivery:"env"`
+	Hosts   []string          `long:"host" env:"HOSTS" env-delim:","`
+	Labels  map[string]string `long:"label" env:"LABELS" env-delim:";" key-value-delimiter:"="`
+	Verbose bool              `short:"v" env:"VERBOSE" delivery:"flag"`
+	Name    string            `long:"name"`
+	Unset   string            `long:"unset" env:"UNSET"`
+	DB      struct {
+		Host string `long:"host" env:"HOST"`
+	} `group:"Database" namespace:"db" env-namespace:"DB"`
+}
+
+func TestEnv(t *testing.T) {
+	opts := envOptions{
+		Token:   "secret",
+		Hosts:   []string{"foo", "bar"},
+		Labels:  map[string]string{"a": "1", "b": "2"},
+		Verbose: true,
+		Name:    "name",
+	}
+	opts.DB.Host = "localhost"
+	env, err := testConfig.Env(opts)
+	expected := []string{"TOKEN=secret", "HOSTS=foo,bar", "LABELS=a=1;b=2", "DB_HOST=localhost"}
+	testArgsAreEqual(t, expected, env, err)
+	args, err := testConfig.Args(opts)
+	expected = []string{"-v", "--host", "foo", "--host", "bar", "--label", "a=1", "--label", "b=2", "--name", "name", "--db.host", "localhost"}
+	testArgsAreEqual(t, expected, args, err)
+
+	config := *testConfig
+	config.Delivery = DeliverEnv
+	config.EnvNamespaceDelimiter = "__"
+	env, err = config.Env(opts)
+	expected = []string{"TOKEN=secret", "HOSTS=foo,bar", "LABELS=a=1;b=2", "DB__HOST=localhost"}
+	testArgsAreEqual(t, expected, env, err)
+	args, err = config.Args(opts)
+	expected = []string{"-v", "--name", "name"}
+	testArgsAreEqual(t, expected, args, err)
+}
+
+func TestEnvWithJoinSeparator(t *testing.T) {
+	s := struct {
+		Tags  []string `long:"tag" env:"TAGS" env-delim:";" join:","`
+		Hosts []string `long:"host" env:"HOSTS" env-delim:" "`
+	}{
+		Tags:  []string{"a", "b"},
+		Hosts: []string{"foo", "bar"},
+	}
+	config := *testConfig
+	config.JoinSeparator = ":"
+	env, err := config.Env(s)
+	testArgsAreEqual(t, []string{"TAGS=a;b", "HOSTS=foo bar"}, env, err)
+	args, err := config.Args(s)
+	testArgsAreEqual(t, []string{"--tag", "a,b", "--host", "foo:bar"}, args, err)
+}
+
+func TestEnvShouldFail(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Struct interface{}
+		Err    string
+	}{
+		{
+			Name: "no env-delim",
+			Struct: struct {
+				Hosts []string `long:"host" env:"HOSTS"`
+			}{
+				Hosts: []string{"foo", "bar"},
+			},
+			Err: "option with multiple values does not have env-delim",
+		},
+		{
+			Name: "value contains env-delim",
+			Struct: struct {
+				Hosts []string `long:"host" env:"HOSTS" env-delim:","`
+			}{
+				Hosts: []string{"foo", "bar,baz"},
+			},
+			Err: `value contains env-delim ","`,
+		},
+		{
+			Name: "unknown delivery",
+			Struct: struct {
+				Host string `long:"host" env:"HOST" delivery:"pipe"`
+			}{
+				Host: "foo",
+			},
+			Err: `unknown delivery "pipe"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if _, err := testConfig.Env(tt.Struct); err == nil || !strings.Contains(err.Error(), tt.Err) {
+				t.Errorf("Config.Env() = _, %v; does not contain %q", err, tt.Err)
+			}
+		})
+	}
+}
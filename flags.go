@@ -1,13 +1,14 @@
 package cmdbuilder
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/fatih/structtag"
 	"github.com/pkg/errors"
 )
 
@@ -17,36 +18,232 @@ type Marshaler interface {
 	MarshalFlag() (string, error)
 }
 
+// ValueSource identifies an interface that a value may implement
+// to convert itself to a string.
+type ValueSource int
+
+const (
+	MarshalerSource     ValueSource = iota // Marshaler
+	TextMarshalerSource                    // encoding.TextMarshaler
+	StringerSource                         // fmt.Stringer
+)
+
 var (
-	durationType  = reflect.TypeOf((*time.Duration)(nil)).Elem()
-	marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	durationType      = reflect.TypeOf((*time.Duration)(nil)).Elem()
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
 )
 
+var defaultValueSources = []ValueSource{MarshalerSource, TextMarshalerSource, StringerSource}
+
+func (s ValueSource) typ() reflect.Type {
+	switch s {
+	case MarshalerSource:
+		return marshalerType
+	case TextMarshalerSource:
+		return textMarshalerType
+	case StringerSource:
+		return stringerType
+	}
+	return nil
+}
+
+func (c *Config) valueSources() []ValueSource {
+	if c.ValueSources == nil {
+		return defaultValueSources
+	}
+	return c.ValueSources
+}
+
+// source returns the first value source implemented by v or a pointer to v.
+func (c *Config) source(v reflect.Value) (reflect.Value, ValueSource, bool) {
+	if !v.CanInterface() {
+		return reflect.Value{}, 0, false
+	}
+	for _, s := range c.valueSources() {
+		typ := s.typ()
+		if typ == nil {
+			continue
+		}
+		if v.Type().Implements(typ) {
+			return v, s, true
+		}
+		if reflect.PtrTo(v.Type()).Implements(typ) {
+			if v.CanAddr() {
+				return v.Addr(), s, true
+			}
+			// v is not addressable (e.g. a field of a struct passed by value
+			// or a map element), so an addressable copy is made.
+			pv := reflect.New(v.Type())
+			pv.Elem().Set(v)
+			return pv, s, true
+		}
+	}
+	return reflect.Value{}, 0, false
+}
+
+// hasSource reports whether the type t or a pointer to t implements any value source.
+func (c *Config) hasSource(t reflect.Type) bool {
+	for _, s := range c.valueSources() {
+		if typ := s.typ(); typ != nil && (t.Implements(typ) || reflect.PtrTo(t).Implements(typ)) {
+			return true
+		}
+	}
+	return false
+}
+
 // arg wraps struct field flag.
 type arg struct {
-	isOption bool
-	st       reflect.Type
-	sf       reflect.StructField
-	tags     *structTags
-	value    reflect.Value
+	kind        ArgKind
+	st          reflect.Type
+	sf          reflect.StructField
+	tags        *structTags
+	long        string   // selected long name
+	short       string   // selected short name
+	path        []string // names of the struct fields from the root struct
+	prefix      string   // namespace prefix of the long name
+	envPrefix   string   // namespace prefix of the environment variable
+	delivery    Delivery
+	value       []string
+	def         []string
+	join        string // separator of values written as a single argument, if any
+	unsupported string // reason the arg is unsupported by the target version, if any
+}
+
+func (c *Config) newArg(kind ArgKind, st reflect.Type, sf reflect.StructField, tags *structTags, v reflect.Value) (arg, error) {
+	a := arg{
+		kind: kind,
+		st:   st,
+		sf:   sf,
+		tags: tags,
+	}
+	if kind == OptionArg {
+		a.long = c.selectName(sf, tags, tags.All("long"))
+		a.short = c.selectName(sf, tags, tags.All("short"))
+	}
+	var err error
+	if a.delivery, err = c.delivery(tags); err != nil {
+		return a, a.error(err)
+	}
+	kvDelim := c.keyValueDelimiter(tags)
+	if a.value, err = c.valueSlice(v, kvDelim); err != nil {
+		return a, a.error(err)
+	}
+	if kind == OptionArg {
+		if a.def = tags.All("default"); a.def == nil {
+			if a.def, err = c.valueSlice(reflect.Zero(sf.Type), kvDelim); err != nil {
+				return a, a.error(err)
+			}
+		}
+	}
+	if sep := c.joinSeparator(tags); sep != "" && kind == OptionArg && c.isMultiValue(sf.Type) && !a.isRepeatable() {
+		if _, err = joinValue(a.value, sep); err != nil {
+			return a, a.error(err)
+		}
+		a.join = sep
+	}
+	if a.isCount() {
+		switch indirectType(sf.Type).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return a, a.error(errors.New("count option does not have integer type"))
+		}
+		if a.value, err = countValue(a.value); err != nil {
+			return a, a.error(err)
+		}
+		if a.def, err = countValue(a.def); err != nil {
+			return a, a.error(err)
+		}
+	}
+	return a, nil
+}
+
+// selectName returns the name of the option written from its long or short names
+// (see Config.NameSelector).
+func (c *Config) selectName(sf reflect.StructField, tags *structTags, names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	if c.NameSelector != nil {
+		if name := c.NameSelector(sf, names); name != "" {
+			return name
+		}
+	}
+	for _, list := range [][]string{c.PreferredNames, tags.All("preferred")} {
+		for _, name := range list {
+			if contains(names, name) {
+				return name
+			}
+		}
+	}
+	return names[0]
+}
+
+func (c *Config) joinSeparator(tags *structTags) string {
+	switch sep := tags.First("join"); sep {
+	case "":
+		return c.JoinSeparator
+	case "none":
+		return ""
+	default:
+		return sep
+	}
+}
+
+// joinValue joins the list of values of a slice or map option with sep.
+func joinValue(list []string, sep string) ([]string, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	for i, v := range list {
+		if strings.Contains(v, sep) {
+			return nil, &elemError{
+				index: i,
+				err:   errors.Errorf("value contains join separator %q", sep),
+			}
+		}
+	}
+	return []string{strings.Join(list, sep)}, nil
+}
+
+// countValue converts the count of a counted option
+// to the list of values of the repeated option.
+func countValue(list []string) ([]string, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(list[0])
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errors.Errorf("negative count %d", n)
+	}
+	var count []string
+	for i := 0; i < n; i++ {
+		count = append(count, "true")
+	}
+	return count, nil
 }
 
 func (a arg) Struct() reflect.Type { return a.st }
 
 func (a arg) Field() reflect.StructField { return a.sf }
 
-func (a arg) IsOption() bool { return a.isOption }
+func (a arg) IsOption() bool { return a.kind == OptionArg }
+
+func (a arg) IsPositional() bool { return a.kind == PositionalArg }
+
+func (a arg) IsCommand() bool { return a.kind == CommandArg }
 
 func (a arg) IsProvided() bool {
-	def := a.tags.All("default")
-	if def == nil {
-		def = valueSlice(reflect.Zero(a.sf.Type))
-	}
-	return !reflect.DeepEqual(a.Value(), def)
+	return !reflect.DeepEqual(a.value, a.def)
 }
 
 func (a arg) IsValueOptional() bool {
-	return a.isOption && (a.tags.IsTrue("optional") || a.isBoolean())
+	return a.IsOption() && (a.tags.IsTrue("optional") || a.isBoolean() || a.isCount())
 }
 
 func (a arg) IsValueProvided() bool {
@@ -56,18 +253,76 @@ func (a arg) IsValueProvided() bool {
 	if !a.IsValueOptional() {
 		return true
 	}
-	return !reflect.DeepEqual(a.Value(), a.tags.All("optional-value")) && (!a.isBoolean() || !a.isTrueValue())
+	return !reflect.DeepEqual(a.Value(), a.tags.All("optional-value")) && (!a.isRepeatable() || !a.isTrueValue())
+}
+
+// IsNegated reports whether the option is a boolean option
+// having the false value, while its default value is true.
+func (a arg) IsNegated() bool {
+	if !a.IsOption() || indirectType(a.sf.Type).Kind() != reflect.Bool {
+		return false
+	}
+	return reflect.DeepEqual(a.value, []string{"false"}) && reflect.DeepEqual(a.def, []string{"true"})
 }
 
 func (a arg) Name() string {
-	return a.tags.First("long")
+	if a.IsCommand() {
+		return a.tags.First("command")
+	}
+	if a.long != "" {
+		return a.prefix + a.long
+	}
+	return ""
 }
 
-func (a arg) ShortName() string {
-	return a.tags.First("short")
+// Names returns all long names of the option,
+// including the name valid for the target version, if any.
+func (a arg) Names() []string {
+	long := a.tags.All("long")
+	if a.long != "" && !contains(long, a.long) {
+		long = append(long, a.long)
+	}
+	var names []string
+	for _, name := range long {
+		names = append(names, a.prefix+name)
+	}
+	return names
 }
 
-func (a arg) Value() []string { return valueSlice(a.value) }
+func (a arg) ShortName() string { return a.short }
+
+// ShortNames returns all short names of the option.
+func (a arg) ShortNames() []string { return a.tags.All("short") }
+
+func (a arg) Value() []string { return a.value }
+
+// argValue returns values of the option written as arguments,
+// joined with the join separator, if any.
+func (a arg) argValue() []string {
+	if a.join == "" {
+		return a.value
+	}
+	joined, _ := joinValue(a.value, a.join)
+	return joined
+}
+
+// EnvName returns the name of the environment variable of the option, if any.
+func (a arg) EnvName() string {
+	if name := a.tags.First("env"); name != "" {
+		return a.envPrefix + name
+	}
+	return ""
+}
+
+// IsFlag reports whether the option is passed as a command-line argument.
+func (a arg) IsFlag() bool {
+	return a.IsOption() && (a.EnvName() == "" || a.delivery != DeliverEnv)
+}
+
+// IsEnv reports whether the option is passed as an environment variable.
+func (a arg) IsEnv() bool {
+	return a.IsOption() && a.EnvName() != "" && a.delivery != DeliverFlag
+}
 
 func (a arg) isBoolean() bool {
 	t := indirectType(a.sf.Type)
@@ -81,6 +336,43 @@ func (a arg) isBoolean() bool {
 	}
 }
 
+func (a arg) error(err error) *FieldError {
+	e := &FieldError{
+		Struct: a.st,
+		Field:  a.sf.Name,
+		Type:   a.sf.Type,
+		Msg:    err.Error(),
+		Err:    err,
+	}
+	if ee, ok := err.(*elemError); ok {
+		e.Index = ee.index
+		e.Msg = ee.err.Error()
+		e.Err = ee.err
+	}
+	return e
+}
+
+func (a arg) kindName() string {
+	switch a.kind {
+	case PositionalArg:
+		return "positional argument"
+	case CommandArg:
+		return "command"
+	default:
+		return "option"
+	}
+}
+
+func (a arg) isCount() bool {
+	return a.IsOption() && a.tags.IsTrue("count")
+}
+
+// isRepeatable reports whether the option is repeated without a value
+// for each true value (e.g. '-vvv').
+func (a arg) isRepeatable() bool {
+	return a.isBoolean() || a.isCount()
+}
+
 func (a arg) isTrueValue() bool {
 	for _, v := range a.Value() {
 		if v != "true" {
@@ -90,29 +382,151 @@ func (a arg) isTrueValue() bool {
 	return true
 }
 
-func parse(v interface{}) (args []arg, err error) {
+// parser holds the state of converting a struct to args.
+type parser struct {
+	c    *Config
+	args []arg
+	cmds []command   // commands found at the current level
+	path []string    // names of the struct fields from the root struct to the current struct
+	ns   []string    // namespaces of the current group followed by delimiters
+	envs []string    // environment namespaces of the current group followed by delimiters
+	errs FieldErrors // type errors found in the strict mode
+
+	keepUnsupported bool // whether to keep args unsupported by the target version
+}
+
+// command wraps struct field command.
+type command struct {
+	st    reflect.Type
+	sf    reflect.StructField
+	tags  *structTags
+	path  []string
+	value reflect.Value
+}
+
+func (c command) Name() string { return c.tags.First("command") }
+
+func (c command) Names() []string {
+	return append([]string{c.Name()}, c.tags.All("alias")...)
+}
+
+func (c *Config) parse(v interface{}) ([]arg, error) {
+	return c.parseAll(v, false)
+}
+
+// parseAll returns args defined by v, including args unsupported
+// by the target version, if keepUnsupported is true.
+func (c *Config) parseAll(v interface{}, keepUnsupported bool) ([]arg, error) {
 	val := reflect.ValueOf(v)
 	if !val.IsValid() {
-		err = errors.New("expected value, got nil")
-		return
+		return nil, errors.New("expected value, got nil")
 	}
 	val = reflect.Indirect(val)
 	if val.Kind() != reflect.Struct {
-		err = errors.Errorf("expected struct, got %s", val.Kind())
-		return
+		return nil, errors.Errorf("expected struct, got %s", val.Kind())
+	}
+	p := &parser{c: c, keepUnsupported: keepUnsupported}
+	if err := p.parseCommand(val, nil, true, 0); err != nil {
+		return nil, err
+	}
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+	return p.args, nil
+}
+
+// parseCommand appends args defined by the command struct v
+// (the root struct if cmd is nil) and its active subcommand.
+func (p *parser) parseCommand(v reflect.Value, cmd *command, emit bool, depth int) error {
+	saved := p.cmds
+	p.cmds = nil
+	err := p.parseStruct(v, emit)
+	cmds := p.cmds
+	p.cmds = saved
+	if err != nil {
+		return err
+	}
+	var active *command
+	if emit {
+		if active, err = p.activeCommand(cmds, depth); err != nil {
+			return err
+		}
+	}
+	if emit && active == nil && cmd != nil && len(cmds) > 0 && !cmd.tags.IsTrue("subcommands-optional") {
+		return &FieldError{
+			Struct: cmd.st,
+			Field:  cmd.sf.Name,
+			Type:   cmd.sf.Type,
+			Msg:    fmt.Sprintf("command %q requires a subcommand", cmd.Name()),
+		}
+	}
+	for i := range cmds {
+		sub := &cmds[i]
+		isActive := emit && sub == active
+		if !isActive && !p.c.Strict {
+			continue
+		}
+		if isActive {
+			p.args = append(p.args, arg{
+				kind: CommandArg,
+				st:   sub.st,
+				sf:   sub.sf,
+				tags: sub.tags,
+				path: sub.path,
+			})
+		}
+		sv := reflect.Indirect(sub.value)
+		if !sv.IsValid() {
+			sv = reflect.Zero(indirectType(sub.sf.Type))
+		}
+		path := p.path
+		p.path = sub.path
+		err = p.parseCommand(sv, sub, isActive, depth+1)
+		p.path = path
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeCommand returns the active command selected either by Config.CommandPath
+// or by a non-nil pointer.
+func (p *parser) activeCommand(cmds []command, depth int) (*command, error) {
+	if depth < len(p.c.CommandPath) {
+		name := p.c.CommandPath[depth]
+		for i, cmd := range cmds {
+			for _, n := range cmd.Names() {
+				if n == name {
+					return &cmds[i], nil
+				}
+			}
+		}
+		return nil, errors.Errorf("unknown command %q", name)
+	}
+	var active *command
+	for i, cmd := range cmds {
+		if cmd.sf.Type.Kind() != reflect.Ptr || cmd.value.Kind() != reflect.Ptr || cmd.value.IsNil() {
+			continue
+		}
+		if active != nil {
+			return nil, errors.Errorf("multiple active commands %q and %q", active.Name(), cmd.Name())
+		}
+		active = &cmds[i]
 	}
-	err = parseStruct(val, &args)
-	return
+	return active, nil
 }
 
-func parseStruct(v reflect.Value, args *[]arg) error {
+// parseStruct appends args defined by the struct v.
+// If emit is false, v is only checked for the unsupported types.
+func (p *parser) parseStruct(v reflect.Value, emit bool) error {
 	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
 		sf := t.Field(i)
 		if sf.PkgPath != "" && !sf.Anonymous {
 			continue
 		}
-		tags, err := parseStructTags(sf.Tag)
+		tags, err := p.c.fieldTags(sf)
 		if err != nil {
 			return &FieldError{
 				Struct: t,
@@ -128,43 +542,140 @@ func parseStruct(v reflect.Value, args *[]arg) error {
 		if !fv.IsValid() {
 			continue
 		}
+		path := append(p.path[:len(p.path):len(p.path)], sf.Name)
+		if tags.First("command") != "" && indirectType(sf.Type).Kind() == reflect.Struct {
+			p.cmds = append(p.cmds, command{
+				st:    t,
+				sf:    sf,
+				tags:  tags,
+				path:  path,
+				value: fv,
+			})
+			continue
+		}
+		if tags.IsTrue("positional") {
+			if err = p.add(PositionalArg, t, sf, tags, fv, emit); err != nil {
+				return err
+			}
+			continue
+		}
 		fv = reflect.Indirect(fv)
+		femit := emit
+		if !fv.IsValid() && p.c.Strict && indirectType(sf.Type).Kind() == reflect.Struct {
+			fv = reflect.Zero(indirectType(sf.Type))
+			femit = false
+		}
+		isOption := tags.First("short") != "" || tags.First("long") != ""
+		if !isOption && p.c.NamingStrategy != nil && !sf.Anonymous && p.c.checkType(sf.Type) == nil {
+			tags.tags = append(tags.tags, Tag{Key: "long", Value: p.c.NamingStrategy(sf.Name)})
+			isOption = true
+		}
 		if fv.Kind() == reflect.Struct {
 			if tags.IsTrue("positional-args") {
+				parent := p.path
+				p.path = path
 				for j := 0; j < fv.NumField(); j++ {
-					*args = append(*args, arg{
-						isOption: false,
-						st:       v.Type(),
-						sf:       sf,
-						value:    fv.Field(j),
-					})
-				}
-			} else {
-				if err = parseStruct(fv, args); err != nil {
-					return &FieldError{
-						Struct: t,
-						Field:  sf.Name,
-						Type:   sf.Type,
-						Msg:    err.Error(),
+					psf := fv.Type().Field(j)
+					ptags, err := p.c.fieldTags(psf)
+					if err != nil {
+						return &FieldError{
+							Struct: fv.Type(),
+							Field:  psf.Name,
+							Type:   psf.Type,
+							Msg:    err.Error(),
+						}
+					}
+					if err = p.add(PositionalArg, fv.Type(), psf, ptags, fv.Field(j), femit); err != nil {
+						return err
 					}
 				}
+				p.path = parent
+				continue
+			}
+			parent, ns, envs := p.path, p.ns, p.envs
+			p.path = path
+			if tags.First("group") != "" {
+				isOption = false
+				if namespace := tags.First("namespace"); namespace != "" {
+					p.ns = append(p.ns[:len(p.ns):len(p.ns)], namespace+p.c.namespaceDelimiter())
+				}
+				if prefix := tags.First("prefix"); prefix != "" {
+					p.ns = append(p.ns[:len(p.ns):len(p.ns)], prefix)
+				}
+				if namespace := tags.First("env-namespace"); namespace != "" {
+					p.envs = append(p.envs[:len(p.envs):len(p.envs)], namespace+p.c.envNamespaceDelimiter())
+				}
+				if prefix := tags.First("env-prefix"); prefix != "" {
+					p.envs = append(p.envs[:len(p.envs):len(p.envs)], prefix)
+				}
+			}
+			err = p.parseStruct(fv, femit)
+			p.path, p.ns, p.envs = parent, ns, envs
+			if err != nil {
+				if _, ok := err.(*FieldError); ok {
+					return err
+				}
+				return &FieldError{
+					Struct: t,
+					Field:  sf.Name,
+					Type:   sf.Type,
+					Msg:    err.Error(),
+				}
 			}
-			continue
 		}
-		if tags.First("short") == "" && tags.First("long") == "" {
+		if !isOption {
 			continue
 		}
-		*args = append(*args, arg{
-			isOption: true,
-			st:       v.Type(),
-			sf:       sf,
-			tags:     tags,
-			value:    fv,
-		})
+		if err = p.add(OptionArg, t, sf, tags, fv, femit); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func (p *parser) add(kind ArgKind, st reflect.Type, sf reflect.StructField, tags *structTags, v reflect.Value, emit bool) error {
+	if p.c.Strict {
+		if err := p.c.checkType(sf.Type); err != nil {
+			p.errs = append(p.errs, &FieldError{
+				Struct: st,
+				Field:  sf.Name,
+				Type:   sf.Type,
+				Msg:    err.Error(),
+				Err:    err,
+			})
+			return nil
+		}
+	}
+	if !emit {
+		return nil
+	}
+	a, err := p.c.newArg(kind, st, sf, tags, v)
+	if err != nil {
+		return err
+	}
+	reason, err := p.c.targetVersion(&a)
+	if err != nil {
+		return a.error(err)
+	}
+	if reason != "" {
+		reason = fmt.Sprintf("%s is %s, target version is %s", a.kindName(), reason, p.c.TargetVersion)
+		if a.IsProvided() && !p.c.DropUnsupported {
+			e := a.error(ErrUnsupportedVersion)
+			e.Msg = reason
+			return e
+		}
+		if !p.keepUnsupported {
+			return nil
+		}
+		a.unsupported = reason
+	}
+	a.path = append(p.path[:len(p.path):len(p.path)], sf.Name)
+	a.prefix = strings.Join(p.ns, "")
+	a.envPrefix = strings.Join(p.envs, "")
+	p.args = append(p.args, a)
+	return nil
+}
+
 func indirect(v reflect.Value) reflect.Value {
 	if !v.IsValid() {
 		return reflect.Value{}
@@ -182,6 +693,27 @@ func indirect(v reflect.Value) reflect.Value {
 	}
 }
 
+// isMultiValue reports whether values of the type t are converted to multiple strings.
+func (c *Config) isMultiValue(t reflect.Type) bool {
+	t = indirectType(t)
+	if c.hasSource(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func isSlice(t reflect.Type) bool {
+	switch indirectType(t).Kind() {
+	case reflect.Array, reflect.Slice:
+		return true
+	}
+	return false
+}
+
 func indirectType(t reflect.Type) reflect.Type {
 	for {
 		switch t.Kind() {
@@ -193,69 +725,189 @@ func indirectType(t reflect.Type) reflect.Type {
 	}
 }
 
-func valueSlice(v reflect.Value) []string {
+// elemError represents an error when converting an element
+// of a slice, an array or a map.
+type elemError struct {
+	index interface{}
+	err   error
+}
+
+func (e *elemError) Error() string {
+	return fmt.Sprintf("element %v: %v", e.index, e.err)
+}
+
+// valueSlice converts v to a list of strings
+// joining keys and values of maps with kvDelim.
+func (c *Config) valueSlice(v reflect.Value, kvDelim string) ([]string, error) {
 	v = indirect(v)
 	if !v.IsValid() {
-		return nil
+		return nil, nil
 	}
 	var list []string
-	switch v.Type().Kind() {
+	kind := v.Type().Kind()
+	if _, _, ok := c.source(v); ok {
+		kind = reflect.Invalid
+	}
+	switch kind {
 	case reflect.Array, reflect.Slice:
 		for i := 0; i < v.Len(); i++ {
-			list = append(list, valueString(v.Index(i)))
+			s, err := c.valueString(v.Index(i))
+			if err != nil {
+				return nil, &elemError{index: i, err: err}
+			}
+			list = append(list, s)
 		}
 	case reflect.Map:
-		keys := v.MapKeys()
-		sort.Slice(keys, func(i, j int) bool {
-			return valueString(keys[i]) < valueString(keys[j])
+		type entry struct{ k, v string }
+		var entries []entry
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := c.valueString(iter.Key())
+			if err != nil {
+				return nil, &elemError{index: mapKey(iter.Key(), k), err: err}
+			}
+			if strings.Contains(k, kvDelim) {
+				return nil, &elemError{
+					index: mapKey(iter.Key(), k),
+					err:   errors.Errorf("key contains key-value delimiter %q", kvDelim),
+				}
+			}
+			s, err := c.valueString(iter.Value())
+			if err != nil {
+				return nil, &elemError{index: mapKey(iter.Key(), k), err: err}
+			}
+			entries = append(entries, entry{k: k, v: s})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].k < entries[j].k
 		})
-		for _, k := range keys {
-			list = append(list, valueString(k)+":"+valueString(v.MapIndex(k)))
+		for _, e := range entries {
+			list = append(list, e.k+kvDelim+e.v)
 		}
 	default:
-		if s := valueString(v); s != "" {
+		s, err := c.valueString(v)
+		if err != nil {
+			return nil, err
+		}
+		if s != "" {
 			list = append(list, s)
 		}
 	}
-	return list
+	return list, nil
+}
+
+func (c *Config) keyValueDelimiter(tags *structTags) string {
+	if delim := tags.First("key-value-delimiter"); delim != "" {
+		return delim
+	}
+	if c.KeyValueDelimiter != "" {
+		return c.KeyValueDelimiter
+	}
+	return ":"
 }
 
-func valueString(v reflect.Value) string {
+func mapKey(k reflect.Value, s string) interface{} {
+	if k.CanInterface() {
+		return k.Interface()
+	}
+	return s
+}
+
+func (c *Config) valueString(v reflect.Value) (string, error) {
 	v = indirect(v)
 	if !v.IsValid() {
-		return ""
+		return "", nil
 	}
 	typ := v.Type()
-	if v.CanInterface() && typ.Implements(marshalerType) {
-		s, err := v.Interface().(Marshaler).MarshalFlag()
+	if mv, src, ok := c.source(v); ok {
+		var (
+			s   string
+			err error
+		)
+		switch src {
+		case MarshalerSource:
+			s, err = mv.Interface().(Marshaler).MarshalFlag()
+		case TextMarshalerSource:
+			var b []byte
+			b, err = mv.Interface().(encoding.TextMarshaler).MarshalText()
+			s = string(b)
+		case StringerSource:
+			s = mv.Interface().(fmt.Stringer).String()
+		}
 		if err != nil {
-			panic(errors.Wrapf(err, "failed to marshal value %q", v))
+			return "", errors.Wrapf(err, "failed to marshal value %q", v)
 		}
-		return s
+		return s, nil
 	}
 	if typ == durationType {
-		return v.Interface().(fmt.Stringer).String()
+		return v.Interface().(fmt.Stringer).String(), nil
 	}
 	switch typ.Kind() {
 	case reflect.Bool:
 		if v.Bool() {
-			return "true"
+			return "true", nil
 		}
-		return "false"
+		return "false", nil
 	case reflect.Float32, reflect.Float64:
-		return strconv.FormatFloat(v.Float(), 'g', -1, typ.Bits())
+		return strconv.FormatFloat(v.Float(), 'g', -1, typ.Bits()), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.FormatInt(v.Int(), 10)
+		return strconv.FormatInt(v.Int(), 10), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.FormatUint(v.Uint(), 10)
+		return strconv.FormatUint(v.Uint(), 10), nil
 	case reflect.String:
-		return v.String()
+		return v.String(), nil
 	}
-	return ""
+	if c.Strict {
+		return "", errors.Errorf("unsupported type %s", typ)
+	}
+	return "", nil
 }
 
+// checkType returns an error if values of the type t can't be converted to args.
+func (c *Config) checkType(t reflect.Type) error {
+	t = indirectType(t)
+	if c.isScalarType(t) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice:
+		if !c.isScalarType(t.Elem()) {
+			return errors.Errorf("unsupported element type %s", t.Elem())
+		}
+		return nil
+	case reflect.Map:
+		if !c.isScalarType(t.Key()) {
+			return errors.Errorf("unsupported key type %s", t.Key())
+		}
+		if !c.isScalarType(t.Elem()) {
+			return errors.Errorf("unsupported element type %s", t.Elem())
+		}
+		return nil
+	}
+	return errors.Errorf("unsupported type %s", t)
+}
+
+// isScalarType reports whether values of the type t are converted to a single string.
+// Interface types are considered scalar, as their dynamic values are checked by valueString.
+func (c *Config) isScalarType(t reflect.Type) bool {
+	t = indirectType(t)
+	if c.hasSource(t) || t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// structTags holds struct field tags in order of appearance.
+// Unlike reflect.StructTag, a key may appear multiple times.
 type structTags struct {
-	tags structtag.Tags
+	tags []Tag
 }
 
 func (t *structTags) All(key string) []string {
@@ -263,9 +915,9 @@ func (t *structTags) All(key string) []string {
 		return nil
 	}
 	var list []string
-	for _, tag := range t.tags.Tags() {
+	for _, tag := range t.tags {
 		if tag.Key == key {
-			list = append(list, tag.Name)
+			list = append(list, tag.Value)
 		}
 	}
 	if len(list) == 1 && list[0] == "" {
@@ -278,8 +930,10 @@ func (t *structTags) First(key string) string {
 	if t == nil {
 		return ""
 	}
-	if tag, err := t.tags.Get(key); err == nil {
-		return tag.Name
+	for _, tag := range t.tags {
+		if tag.Key == key {
+			return tag.Value
+		}
 	}
 	return ""
 }
@@ -289,10 +943,41 @@ func (t *structTags) IsTrue(key string) bool {
 	return v != "" && v != "false" && v != "no" && v != "0"
 }
 
+// parseStructTags parses tag in the conventional format of reflect.StructTag
+// keeping values as is, including commas.
 func parseStructTags(tag reflect.StructTag) (*structTags, error) {
-	tags, err := structtag.Parse(string(tag))
-	if err != nil {
-		return nil, err
+	var tags []Tag
+	s := string(tag)
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			break
+		}
+		i := 0
+		for i < len(s) && s[i] > ' ' && s[i] != ':' && s[i] != '"' && s[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(s) || s[i] != ':' || s[i+1] != '"' {
+			return nil, errors.Errorf("bad syntax for struct tag pair %q", s)
+		}
+		key := s[:i]
+		s = s[i+1:]
+		i = 1
+		for i < len(s) && s[i] != '"' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return nil, errors.Errorf("bad syntax for struct tag value %q", key)
+		}
+		value, err := strconv.Unquote(s[:i+1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad syntax for struct tag value %q", key)
+		}
+		s = s[i+1:]
+		tags = append(tags, Tag{Key: key, Value: value})
 	}
-	return &structTags{tags: *tags}, nil
+	return &structTags{tags: tags}, nil
 }
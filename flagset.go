@@ -0,0 +1,89 @@
+package cmdbuilder
+
+import (
+	"flag"
+	"strings"
+	"unicode/utf8"
+)
+
+// boolFlag is the optional interface implemented by flag.Value of boolean flags.
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// FlagSetArgs converts flags of the provided flag set fs and its remaining
+// positional arguments to command-line arguments using the provided configuration c.
+//
+// If all is false, only flags that have been set are converted,
+// as flag.FlagSet.Visit visits them. Otherwise, every flag
+// which value differs from its default value is converted.
+//
+// Flags with single character names are written as short options,
+// unless DisableShortName is true, others as long options.
+// Short options are never combined.
+func (c *Config) FlagSetArgs(fs *flag.FlagSet, all bool) []string {
+	return c.flagSetArgs(fs, all, false)
+}
+
+// FlagSetCommandLine converts flags of the provided flag set fs and its remaining
+// positional arguments to a command-line using the provided configuration c
+// (see Config.FlagSetArgs).
+func (c *Config) FlagSetCommandLine(fs *flag.FlagSet, all bool) string {
+	return strings.Join(c.flagSetArgs(fs, all, true), " ")
+}
+
+func (c *Config) flagSetArgs(fs *flag.FlagSet, all, cmdline bool) []string {
+	var args []string
+	visit := func(f *flag.Flag) {
+		args = append(args, c.flagArgs(f, cmdline)...)
+	}
+	if all {
+		fs.VisitAll(func(f *flag.Flag) {
+			if f.Value.String() != f.DefValue {
+				visit(f)
+			}
+		})
+	} else {
+		fs.Visit(visit)
+	}
+	return c.appendPositional(args, fs.Args(), cmdline)
+}
+
+func (c *Config) flagArgs(f *flag.Flag, cmdline bool) []string {
+	var opt string
+	if utf8.RuneCountInString(f.Name) == 1 && !c.DisableShortName {
+		opt = c.ShortOptionDelimiter + f.Name
+	} else {
+		opt = c.longOptionDelimiter() + f.Name
+	}
+	v := f.Value.String()
+	if b, ok := f.Value.(boolFlag); ok && b.IsBoolFlag() {
+		if v == "true" {
+			return []string{opt}
+		}
+		// Boolean flags never take separate arguments.
+		delim := c.OptionOptionalArgumentDelimiter
+		if delim == "" || delim == " " {
+			delim = "="
+		}
+		if cmdline {
+			v = c.quote(v)
+		}
+		return []string{opt + delim + v}
+	}
+	return c.optionArgument(opt, v, false, cmdline)
+}
+
+// FlagSetArgs converts flags of the provided flag set fs and its remaining
+// positional arguments to command-line arguments
+// using a default configuration (see Config.FlagSetArgs).
+func FlagSetArgs(fs *flag.FlagSet, all bool) []string {
+	return defaultConfig.FlagSetArgs(fs, all)
+}
+
+// FlagSetCommandLine converts flags of the provided flag set fs and its remaining
+// positional arguments to a command-line
+// using a default configuration (see Config.FlagSetArgs).
+func FlagSetCommandLine(fs *flag.FlagSet, all bool) string {
+	return defaultConfig.FlagSetCommandLine(fs, all)
+}
@@ -0,0 +1,80 @@
+package cmdbuilder
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("v", false, "")
+	fs.Bool("color", true, "")
+	fs.String("name", "", "")
+	fs.Int("count", 1, "")
+	fs.Duration("timeout", time.Second, "")
+	return fs
+}
+
+func TestFlagSetArgs(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Args         []string
+		All          bool
+		Config       *Config
+		ExpectedArgs []string
+		ExpectedCmd  string
+	}{
+		{
+			Name:         "visit",
+			Args:         []string{"-v", "-color=false", "-name", "foo bar", "-count=1", "--", "-arg"},
+			Config:       GoConfig,
+			ExpectedArgs: []string{"-color=false", "-count=1", "-name=foo bar", "-v", "--", "-arg"},
+			ExpectedCmd:  `-color=false -count=1 -name="foo bar" -v -- -arg`,
+		},
+		{
+			Name:         "visit all",
+			Args:         []string{"-v", "-color=false", "-name", "foo bar", "-count=1", "-timeout=1m", "arg"},
+			All:          true,
+			Config:       GoConfig,
+			ExpectedArgs: []string{"-color=false", "-name=foo bar", "-timeout=1m0s", "-v", "arg"},
+			ExpectedCmd:  `-color=false -name="foo bar" -timeout=1m0s -v arg`,
+		},
+		{
+			Name:         "UnixConfig",
+			Args:         []string{"-v", "-color=false", "-name", "foo bar"},
+			Config:       UnixConfig,
+			ExpectedArgs: []string{"--color=false", "--name", "foo bar", "-v"},
+			ExpectedCmd:  `--color=false --name "foo bar" -v`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := newTestFlagSet()
+			if err := fs.Parse(tt.Args); err != nil {
+				t.Fatalf("flag.FlagSet.Parse() = %v; want nil", err)
+			}
+			args := tt.Config.FlagSetArgs(fs, tt.All)
+			if !reflect.DeepEqual(args, tt.ExpectedArgs) {
+				t.Errorf("Config.FlagSetArgs() = %q; want %q", args, tt.ExpectedArgs)
+			}
+			if cmd := tt.Config.FlagSetCommandLine(fs, tt.All); cmd != tt.ExpectedCmd {
+				t.Errorf("Config.FlagSetCommandLine() = %v; want %v", cmd, tt.ExpectedCmd)
+			}
+
+			parsed := newTestFlagSet()
+			if err := parsed.Parse(args); err != nil {
+				t.Fatalf("flag.FlagSet.Parse() = %v; want nil", err)
+			}
+			fs.VisitAll(func(f *flag.Flag) {
+				if v := parsed.Lookup(f.Name).Value.String(); v != f.Value.String() {
+					t.Errorf("flag %s = %q; want %q", f.Name, v, f.Value.String())
+				}
+			})
+			if !reflect.DeepEqual(parsed.Args(), fs.Args()) {
+				t.Errorf("flag.FlagSet.Args() = %q; want %q", parsed.Args(), fs.Args())
+			}
+		})
+	}
+}
@@ -3,9 +3,13 @@ module github.com/sergeymakinen/go-cmdbuilder/v2
 go 1.17
 
 require (
-	github.com/fatih/structtag v1.2.0
+	github.com/alecthomas/kong v0.6.1
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/ompluscator/dynamic-struct v1.3.0
 	github.com/pkg/errors v0.9.1
 	github.com/sergeymakinen/go-quote v1.1.0
+	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
 )
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,86 @@
+// Package argv implements writing options and positional arguments
+// shared by cmdbuilder and its subpackages.
+package argv
+
+import "strings"
+
+// Syntax defines how options and positional arguments are written.
+// See the fields of the same name of cmdbuilder.Config.
+type Syntax struct {
+	ShortOptionDelimiter string
+
+	// LongOptionDelimiter precedes long names,
+	// it's ShortOptionDelimiter for single-dash long options.
+	LongOptionDelimiter string
+
+	OptionArgumentDelimiter   string
+	OptionsTerminator         string
+	OptionsTerminatorIfNeeded bool
+	ArgumentQuoter            func(s string) string
+}
+
+// OptionName returns the option name preceded by ShortOptionDelimiter,
+// if short is true, or by LongOptionDelimiter otherwise.
+func (s *Syntax) OptionName(name string, short bool) string {
+	if short {
+		return s.ShortOptionDelimiter + name
+	}
+	return s.LongOptionDelimiter + name
+}
+
+// Option returns the option opt followed by its argument v
+// either as separate arguments or as a single one, if attach is true.
+// v is quoted, if cmdline is true.
+func (s *Syntax) Option(opt, v string, attach, cmdline bool) []string {
+	if cmdline {
+		v = s.Quote(v)
+	}
+	if attach {
+		return []string{opt + v}
+	}
+	if s.OptionArgumentDelimiter != "" && s.OptionArgumentDelimiter != " " {
+		return []string{opt + s.OptionArgumentDelimiter + v}
+	}
+	return []string{opt, v}
+}
+
+// AppendPositional appends positional arguments to args
+// preceded by the options terminator, if needed.
+// Arguments are quoted, if cmdline is true.
+func (s *Syntax) AppendPositional(args, positional []string, cmdline bool) []string {
+	if s.needsOptionsTerminator(positional) {
+		args = append(args, s.OptionsTerminator)
+	}
+	for _, v := range positional {
+		if cmdline {
+			args = append(args, s.Quote(v))
+		} else {
+			args = append(args, v)
+		}
+	}
+	return args
+}
+
+func (s *Syntax) needsOptionsTerminator(positional []string) bool {
+	if s.OptionsTerminator == "" || len(positional) == 0 {
+		return false
+	}
+	if !s.OptionsTerminatorIfNeeded {
+		return true
+	}
+	for _, v := range positional {
+		if (s.ShortOptionDelimiter != "" && strings.HasPrefix(v, s.ShortOptionDelimiter)) ||
+			(s.LongOptionDelimiter != "" && strings.HasPrefix(v, s.LongOptionDelimiter)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Quote returns v quoted by ArgumentQuoter, if any.
+func (s *Syntax) Quote(v string) string {
+	if s.ArgumentQuoter != nil {
+		return s.ArgumentQuoter(v)
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package argv
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSyntax(t *testing.T) {
+	s := &Syntax{
+		ShortOptionDelimiter:      "-",
+		LongOptionDelimiter:       "--",
+		OptionsTerminator:         "--",
+		OptionsTerminatorIfNeeded: true,
+		ArgumentQuoter:            strconv.Quote,
+	}
+	if opt := s.OptionName("name", false); opt != "--name" {
+		t.Errorf("Syntax.OptionName() = %q; want %q", opt, "--name")
+	}
+	if opt := s.OptionName("n", true); opt != "-n" {
+		t.Errorf("Syntax.OptionName() = %q; want %q", opt, "-n")
+	}
+	if args := s.Option("--name", "foo bar", false, true); !reflect.DeepEqual(args, []string{"--name", `"foo bar"`}) {
+		t.Errorf("Syntax.Option() = %q; want %q", args, []string{"--name", `"foo bar"`})
+	}
+	if args := s.Option("-n", "foo", true, false); !reflect.DeepEqual(args, []string{"-nfoo"}) {
+		t.Errorf("Syntax.Option() = %q; want %q", args, []string{"-nfoo"})
+	}
+	if args := s.AppendPositional(nil, []string{"a", "b"}, false); !reflect.DeepEqual(args, []string{"a", "b"}) {
+		t.Errorf("Syntax.AppendPositional() = %q; want %q", args, []string{"a", "b"})
+	}
+	if args := s.AppendPositional([]string{"-v"}, []string{"-a", "b"}, false); !reflect.DeepEqual(args, []string{"-v", "--", "-a", "b"}) {
+		t.Errorf("Syntax.AppendPositional() = %q; want %q", args, []string{"-v", "--", "-a", "b"})
+	}
+	s.OptionArgumentDelimiter = "="
+	if args := s.Option("--name", "foo", false, false); !reflect.DeepEqual(args, []string{"--name=foo"}) {
+		t.Errorf("Syntax.Option() = %q; want %q", args, []string{"--name=foo"})
+	}
+}
@@ -0,0 +1,84 @@
+package cmdbuilder
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy returns the long name of an option derived from the struct field name.
+type NamingStrategy func(field string) string
+
+// NameSelector returns the name written for the option defined by the struct field sf
+// from its long or short names (without namespaces), e.g. 'color' and 'colour'.
+// If an empty string is returned, the name is selected as if NameSelector is nil.
+type NameSelector func(sf reflect.StructField, names []string) string
+
+// KebabCase returns the field name in kebab case, e.g. 'HTTPProxy' becomes 'http-proxy'.
+func KebabCase(field string) string {
+	return strings.ToLower(strings.Join(splitWords(field), "-"))
+}
+
+// SnakeCase returns the field name in snake case, e.g. 'HTTPProxy' becomes 'http_proxy'.
+func SnakeCase(field string) string {
+	return strings.ToLower(strings.Join(splitWords(field), "_"))
+}
+
+// CamelCase returns the field name in lower camel case, e.g. 'HTTPProxy' becomes 'httpProxy'.
+func CamelCase(field string) string {
+	words := splitWords(field)
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		words[i] = w
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits s to words on changes of the letter case and between letters and digits,
+// so 'HTTPServer2' becomes 'HTTP', 'Server', '2'. Other characters separate words.
+func splitWords(s string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLower(r):
+			return 1
+		case unicode.IsUpper(r):
+			return 2
+		case unicode.IsDigit(r):
+			return 3
+		default:
+			return 0
+		}
+	}
+	var runs [][]rune
+	last := -1
+	for _, r := range s {
+		c := class(r)
+		if c == last && len(runs) > 0 {
+			runs[len(runs)-1] = append(runs[len(runs)-1], r)
+		} else {
+			runs = append(runs, []rune{r})
+		}
+		last = c
+	}
+	// Move the last upper case letter to the following lower case run,
+	// e.g. 'HTTPS', 'erver' to 'HTTP', 'Server'.
+	for i := 0; i < len(runs)-1; i++ {
+		if len(runs[i]) > 0 && unicode.IsUpper(runs[i][0]) && unicode.IsLower(runs[i+1][0]) {
+			n := len(runs[i]) - 1
+			runs[i+1] = append([]rune{runs[i][n]}, runs[i+1]...)
+			runs[i] = runs[i][:n]
+		}
+	}
+	var words []string
+	for _, run := range runs {
+		if len(run) > 0 && class(run[0]) != 0 {
+			words = append(words, string(run))
+		}
+	}
+	return words
+}
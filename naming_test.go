@@ -0,0 +1,153 @@
+package cmdbuilder
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	tests := []struct {
+		Field string
+		Kebab string
+		Snake string
+		Camel string
+	}{
+		{"Name", "name", "name", "name"},
+		{"HTTPProxy", "http-proxy", "http_proxy", "httpProxy"},
+		{"MaxRetries2", "max-retries-2", "max_retries_2", "maxRetries2"},
+		{"user_ID", "user-id", "user_id", "userId"},
+	}
+	for _, tt := range tests {
+		if name := KebabCase(tt.Field); name != tt.Kebab {
+			t.Errorf("KebabCase(%q) = %q; want %q", tt.Field, name, tt.Kebab)
+		}
+		if name := SnakeCase(tt.Field); name != tt.Snake {
+			t.Errorf("SnakeCase(%q) = %q; want %q", tt.Field, name, tt.Snake)
+		}
+		if name := CamelCase(tt.Field); name != tt.Camel {
+			t.Errorf("CamelCase(%q) = %q; want %q", tt.Field, name, tt.Camel)
+		}
+	}
+}
+
+type untaggedOptions struct {
+	Verbose   bool `short:"v"`
+	HTTPProxy string
+	Timeout   time.Duration
+	Since     time.Time
+	Ports     []int
+	Skipped   string `no-flag:"true"`
+	Handler   func()
+	Group     struct {
+		MaxConns int
+	} `group:"g" namespace:"pool"`
+	internal string
+}
+
+func TestArgsWithNamingStrategy(t *testing.T) {
+	opts := untaggedOptions{
+		Verbose:   true,
+		HTTPProxy: "http://proxy",
+		Timeout:   time.Minute,
+		Since:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ports:     []int{80, 443},
+		Skipped:   "skipped",
+		Handler:   func() {},
+		internal:  "internal",
+	}
+	opts.Group.MaxConns = 10
+	tests := []struct {
+		Name     string
+		Strategy NamingStrategy
+		Expected []string
+	}{
+		{
+			Name:     "nil",
+			Expected: []string{"-v"},
+		},
+		{
+			Name:     "KebabCase",
+			Strategy: KebabCase,
+			Expected: []string{"-v", "--http-proxy", "http://proxy", "--timeout", "1m0s", "--since", "2020-01-02T03:04:05Z", "--ports", "80", "--ports", "443", "--pool.max-conns", "10"},
+		},
+		{
+			Name:     "SnakeCase",
+			Strategy: SnakeCase,
+			Expected: []string{"-v", "--http_proxy", "http://proxy", "--timeout", "1m0s", "--since", "2020-01-02T03:04:05Z", "--ports", "80", "--ports", "443", "--pool.max_conns", "10"},
+		},
+		{
+			Name:     "custom",
+			Strategy: func(field string) string { return "x-" + CamelCase(field) },
+			Expected: []string{"-v", "--x-httpProxy", "http://proxy", "--x-timeout", "1m0s", "--x-since", "2020-01-02T03:04:05Z", "--x-ports", "80", "--x-ports", "443", "--pool.x-maxConns", "10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			c := *UnixConfig
+			c.NamingStrategy = tt.Strategy
+			args, err := c.Args(opts)
+			testArgsAreEqual(t, tt.Expected, args, err)
+		})
+	}
+}
+
+type aliasedOptions struct {
+	Color   string `long:"color" long:"colour" short:"c" short:"C"`
+	Verbose bool   `long:"verbose" long:"loud" short:"v" preferred:"loud"`
+	Output  string `long:"output" long:"out" preferred:"out" preferred:"output"`
+}
+
+func TestArgsWithPreferredNames(t *testing.T) {
+	opts := aliasedOptions{Color: "auto", Verbose: true, Output: "file"}
+	tests := []struct {
+		Name     string
+		Config   Config
+		Expected []string
+	}{
+		{
+			Name:     "tags",
+			Config:   Config{DisableShortName: true, LongOptionDelimiter: "--", OptionArgumentDelimiter: "="},
+			Expected: []string{"--color=auto", "--loud", "--out=file"},
+		},
+		{
+			Name:     "config",
+			Config:   Config{ShortOptionDelimiter: "-", LongOptionDelimiter: "--", OptionArgumentDelimiter: "=", DisableCombiningShortOptions: true, PreferredNames: []string{"C", "verbose", "output"}},
+			Expected: []string{"-C=auto", "-v", "--output=file"},
+		},
+		{
+			Name: "selector",
+			Config: Config{
+				DisableShortName:        true,
+				LongOptionDelimiter:     "--",
+				OptionArgumentDelimiter: "=",
+				PreferredNames:          []string{"output"},
+				NameSelector: func(sf reflect.StructField, names []string) string {
+					if sf.Name == "Output" {
+						return ""
+					}
+					return names[len(names)-1]
+				},
+			},
+			Expected: []string{"--colour=auto", "--loud", "--output=file"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			args, err := tt.Config.Args(opts)
+			testArgsAreEqual(t, tt.Expected, args, err)
+		})
+	}
+
+	c := Config{}
+	parsed, err := c.parse(opts)
+	if err != nil {
+		t.Fatalf("Config.parse() = _, %v; want nil", err)
+	}
+	if names := parsed[0].Names(); !reflect.DeepEqual(names, []string{"color", "colour"}) {
+		t.Errorf("arg.Names() = %q; want %q", names, []string{"color", "colour"})
+	}
+	if names := parsed[0].ShortNames(); !reflect.DeepEqual(names, []string{"c", "C"}) {
+		t.Errorf("arg.ShortNames() = %q; want %q", names, []string{"c", "C"})
+	}
+}
@@ -0,0 +1,122 @@
+package cmdbuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrRequired is the underlying error of a FieldError
+	// returned for a required option or positional argument without a value.
+	ErrRequired = errors.New("required value is not provided")
+
+	// ErrInvalidChoice is the underlying error of a FieldError
+	// returned for an option value not listed by the choice tags.
+	ErrInvalidChoice = errors.New("invalid choice")
+)
+
+// Check validates the provided struct (or pointer to a struct) v
+// defining command-line options and their values
+// using the provided configuration c.
+//
+// It returns FieldErrors describing every option or positional argument
+// marked with the required tag, which doesn't have a value, and
+// every option which value is not listed by its choice tags.
+func (c *Config) Check(v interface{}) error {
+	parsed, err := c.parse(v)
+	if err != nil {
+		return err
+	}
+	return validate(parsed)
+}
+
+func validate(parsed []arg) error {
+	var errs FieldErrors
+	for _, arg := range parsed {
+		if arg.IsCommand() {
+			continue
+		}
+		if err := validateRequired(arg); err != nil {
+			errs = append(errs, err)
+		}
+		if err := validateChoice(arg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateRequired(a arg) *FieldError {
+	required := a.tags.First("required")
+	if !a.tags.IsTrue("required") {
+		return nil
+	}
+	if a.IsOption() {
+		if a.IsProvided() {
+			return nil
+		}
+		e := a.error(ErrRequired)
+		e.Msg = "option is required"
+		return e
+	}
+	min := 1
+	if n, err := strconv.Atoi(required); err == nil && isSlice(a.sf.Type) {
+		min = n
+	}
+	if len(a.Value()) >= min {
+		return nil
+	}
+	e := a.error(ErrRequired)
+	if min == 1 {
+		e.Msg = "positional argument is required"
+	} else {
+		e.Msg = fmt.Sprintf("positional argument requires at least %d values, got %d", min, len(a.Value()))
+	}
+	return e
+}
+
+func validateChoice(a arg) *FieldError {
+	choices := a.tags.All("choice")
+	if !a.IsOption() || choices == nil || !a.IsProvided() {
+		return nil
+	}
+	for i, v := range a.Value() {
+		if contains(choices, v) {
+			continue
+		}
+		e := a.error(ErrInvalidChoice)
+		if isSlice(a.sf.Type) {
+			e.Index = i
+		}
+		e.Msg = fmt.Sprintf(`value %q is not one of "%s"`, v, strings.Join(choices, `", "`))
+		return e
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isConstraintError reports whether err is a constraint violation found by Check.
+func isConstraintError(err error) bool {
+	return err == ErrRequired || err == ErrInvalidChoice
+}
+
+// Check validates the provided struct (or pointer to a struct) v
+// defining command-line options and their values
+// using a default configuration (see Config.Check).
+func Check(v interface{}) error {
+	return defaultConfig.Check(v)
+}
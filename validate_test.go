@@ -0,0 +1,116 @@
+package cmdbuilder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type validateOptions struct {
+	Name       string   `short:"n" long:"name" required:"true"`
+	Animal     string   `long:"animal" choice:"cat" choice:"dog"`
+	Animals    []string `long:"animals" choice:"cat" choice:"dog"`
+	Optional   string   `long:"optional" required:"false"`
+	Positional struct {
+		Src  string   `required:"yes"`
+		Dsts []string `required:"2"`
+	} `positional-args:"true"`
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Struct   validateOptions
+		Expected []string
+	}{
+		{
+			Name: "valid",
+			Struct: func() validateOptions {
+				var opts validateOptions
+				opts.Name = "foo"
+				opts.Animal = "cat"
+				opts.Animals = []string{"dog"}
+				opts.Positional.Src = "src"
+				opts.Positional.Dsts = []string{"dst1", "dst2"}
+				return opts
+			}(),
+			Expected: nil,
+		},
+		{
+			Name: "invalid",
+			Struct: func() validateOptions {
+				var opts validateOptions
+				opts.Animal = "horse"
+				opts.Animals = []string{"dog", "cow"}
+				opts.Positional.Dsts = []string{"dst1"}
+				return opts
+			}(),
+			Expected: []string{
+				`invalid value of struct field cmdbuilder.validateOptions.Name of type string: option is required`,
+				`invalid value of struct field cmdbuilder.validateOptions.Animal of type string: value "horse" is not one of "cat", "dog"`,
+				`invalid value of struct field cmdbuilder.validateOptions.Animals[1] of type []string: value "cow" is not one of "cat", "dog"`,
+				`invalid value of struct field struct { Src string "required:\"yes\""; Dsts []string "required:\"2\"" }.Src of type string: positional argument is required`,
+				`invalid value of struct field struct { Src string "required:\"yes\""; Dsts []string "required:\"2\"" }.Dsts of type []string: positional argument requires at least 2 values, got 1`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := testConfig.Check(tt.Struct)
+			if tt.Expected == nil {
+				if err != nil {
+					t.Fatalf("Config.Check() = %v; want nil", err)
+				}
+				return
+			}
+			errs, ok := err.(FieldErrors)
+			if !ok {
+				t.Fatalf("Config.Check() = %v; want FieldErrors", err)
+			}
+			var msgs []string
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+			}
+			if !reflect.DeepEqual(msgs, tt.Expected) {
+				t.Errorf("Config.Check() = %q; want %q", msgs, tt.Expected)
+			}
+		})
+	}
+}
+
+func TestPackageCheck(t *testing.T) {
+	var opts validateOptions
+	if err := Check(opts); err == nil {
+		t.Errorf("Check() = nil; want error")
+	}
+	opts.Name = "foo"
+	opts.Positional.Src = "src"
+	opts.Positional.Dsts = []string{"dst1", "dst2"}
+	if err := Check(opts); err != nil {
+		t.Errorf("Check() = %v; want nil", err)
+	}
+}
+
+func TestArgsWithValidate(t *testing.T) {
+	var opts validateOptions
+	opts.Animal = "horse"
+	opts.Positional.Src = "src"
+	opts.Positional.Dsts = []string{"dst1", "dst2"}
+	args, err := testConfig.Args(opts)
+	testArgsAreEqual(t, []string{"--animal", "horse", "src", "dst1", "dst2"}, args, err)
+
+	config := *testConfig
+	config.Validate = true
+	_, err = config.Args(opts)
+	errs, ok := err.(FieldErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Config.Args() = _, %v; want 2 FieldErrors", err)
+	}
+	if !errors.Is(errs[0], ErrRequired) {
+		t.Errorf("errors.Is(%v, ErrRequired) = false; want true", errs[0])
+	}
+	if !errors.Is(errs[1], ErrInvalidChoice) {
+		t.Errorf("errors.Is(%v, ErrInvalidChoice) = false; want true", errs[1])
+	}
+}
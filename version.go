@@ -0,0 +1,108 @@
+package cmdbuilder
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupportedVersion is the underlying error of a FieldError
+// returned for an option or positional argument having a value
+// while it's not supported by Config.TargetVersion.
+var ErrUnsupportedVersion = errors.New("unsupported by target version")
+
+// targetVersion sets the long name of the arg a valid for Config.TargetVersion
+// and returns the reason why a is unsupported by that version, if it is.
+func (c *Config) targetVersion(a *arg) (string, error) {
+	if c.TargetVersion == "" {
+		return "", nil
+	}
+	if since := a.tags.First("since"); since != "" {
+		n, err := compareVersions(c.TargetVersion, since)
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "added in version " + since, nil
+		}
+	}
+	if until := a.tags.First("until"); until != "" {
+		n, err := compareVersions(c.TargetVersion, until)
+		if err != nil {
+			return "", err
+		}
+		if n >= 0 {
+			return "removed in version " + until, nil
+		}
+	}
+	// The name valid for the target version is the one renamed
+	// in the earliest version after the target version.
+	var renamedIn string
+	for _, s := range a.tags.All("renamed-from") {
+		i := strings.LastIndex(s, "@")
+		if i <= 0 || i == len(s)-1 {
+			return "", errors.Errorf("invalid renamed-from %q", s)
+		}
+		name, version := s[:i], s[i+1:]
+		n, err := compareVersions(c.TargetVersion, version)
+		if err != nil {
+			return "", err
+		}
+		if n >= 0 {
+			continue
+		}
+		if renamedIn != "" {
+			if n, err = compareVersions(version, renamedIn); err != nil {
+				return "", err
+			}
+			if n >= 0 {
+				continue
+			}
+		}
+		a.long, renamedIn = name, version
+	}
+	return "", nil
+}
+
+// compareVersions compares dotted numeric versions a and b (with an optional 'v' prefix)
+// and returns -1, 0 or +1 if a is less than, equal to or greater than b respectively.
+// Missing components are considered zero, so '2' is equal to '2.0.0'.
+func compareVersions(a, b string) (int, error) {
+	x, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(x) || i < len(y); i++ {
+		var m, n int
+		if i < len(x) {
+			m = x[i]
+		}
+		if i < len(y) {
+			n = y[i]
+		}
+		switch {
+		case m < n:
+			return -1, nil
+		case m > n:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(s string) ([]int, error) {
+	var version []int
+	for _, part := range strings.Split(strings.TrimPrefix(s, "v"), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid version %q", s)
+		}
+		version = append(version, n)
+	}
+	return version, nil
+}
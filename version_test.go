@@ -0,0 +1,101 @@
+package cmdbuilder
+
+import (
+	"errors"
+	"testing"
+)
+
+type versionedOptions struct {
+	Color  string `long:"color" renamed-from:"colour@2.0" renamed-from:"colours@1.5"`
+	Jobs   int    `short:"j" long:"jobs" since:"2.3"`
+	Legacy bool   `long:"legacy" until:"3.0"`
+	Args   struct {
+		Path string `since:"2.0"`
+	} `positional-args:"true"`
+}
+
+func TestArgsWithTargetVersion(t *testing.T) {
+	opts := versionedOptions{Color: "auto", Jobs: 4, Legacy: true}
+	opts.Args.Path = "src"
+	tests := []struct {
+		Version  string
+		Drop     bool
+		Expected []string
+		Err      string
+	}{
+		{
+			Version:  "",
+			Expected: []string{"--color", "auto", "-j", "4", "--legacy", "src"},
+		},
+		{
+			Version:  "2.3",
+			Expected: []string{"--color", "auto", "-j", "4", "--legacy", "src"},
+		},
+		{
+			Version: "v3",
+			Err:     "failed to convert struct field cmdbuilder.versionedOptions.Legacy of type bool: option is removed in version 3.0, target version is v3",
+		},
+		{
+			Version:  "3.0.1",
+			Drop:     true,
+			Expected: []string{"--color", "auto", "-j", "4", "src"},
+		},
+		{
+			Version: "2.2.9",
+			Err:     "failed to convert struct field cmdbuilder.versionedOptions.Jobs of type int: option is added in version 2.3, target version is 2.2.9",
+		},
+		{
+			Version:  "1.9",
+			Drop:     true,
+			Expected: []string{"--colour", "auto", "--legacy"},
+		},
+		{
+			Version:  "1.4",
+			Drop:     true,
+			Expected: []string{"--colours", "auto", "--legacy"},
+		},
+		{
+			Version: "2.x",
+			Err:     `failed to convert struct field cmdbuilder.versionedOptions.Color of type string: invalid version "2.x"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Version, func(t *testing.T) {
+			c := *UnixConfig
+			c.TargetVersion = tt.Version
+			c.DropUnsupported = tt.Drop
+			args, err := c.Args(opts)
+			if tt.Err != "" {
+				if err == nil || err.Error() != tt.Err {
+					t.Fatalf("Config.Args() = _, %v; want %s", err, tt.Err)
+				}
+				return
+			}
+			testArgsAreEqual(t, tt.Expected, args, err)
+		})
+	}
+
+	c := *UnixConfig
+	c.TargetVersion = "3.0"
+	if _, err := c.Args(opts); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Config.Args() = _, %v; want %v", err, ErrUnsupportedVersion)
+	}
+	args, err := c.Args(versionedOptions{Color: "auto"})
+	testArgsAreEqual(t, []string{"--color", "auto"}, args, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		A, B     string
+		Expected int
+	}{
+		{"1", "1.0.0", 0},
+		{"v1.2", "1.10", -1},
+		{"2.0.1", "2", 1},
+	}
+	for _, tt := range tests {
+		if n, err := compareVersions(tt.A, tt.B); err != nil || n != tt.Expected {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want %d, nil", tt.A, tt.B, n, err, tt.Expected)
+		}
+	}
+}